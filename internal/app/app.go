@@ -2,18 +2,26 @@ package app
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/config"
 	"gitea.kood.tech/ivanandreev/viewer/internal/controller/httpserver"
+	"gitea.kood.tech/ivanandreev/viewer/internal/controller/httpserver/handlers"
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/adapter"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+	"gitea.kood.tech/ivanandreev/viewer/internal/repository/jsonfile"
 	"gitea.kood.tech/ivanandreev/viewer/internal/repository/webapi"
 	"gitea.kood.tech/ivanandreev/viewer/internal/usecase/carstore"
 	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
 	"gitea.kood.tech/ivanandreev/viewer/pkg/httpclient"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/lifecycle"
 	"gitea.kood.tech/ivanandreev/viewer/pkg/logger"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/redis"
 )
 
 // This struct holds your entire running application
@@ -29,36 +37,25 @@ func New(cfg *config.Config) *App {
 	}
 }
 
-func (app *App) Run() error {
+// Components have this long to stop on shutdown, all together
+const shutdownTimeout = 5 * time.Second
 
-	appCtx, cancelApp := context.WithCancel(context.Background())
-	defer cancelApp()
+func (app *App) Run() error {
 
 	// Logger: slog
 	app.log.Info("starting car viewer", slog.String("env", app.cfg.Env))
 	app.log.Debug("debug messages are enabled")
 
-	// Client
-	client := httpclient.New(
-		app.cfg.Client.Host,
-		app.cfg.Client.Timeout,
-	)
-
-	// Repository - Storage layer (WebAPI storage)
-	repo := webapi.New(
-		app.log,
-		client,
-	)
-
-	// Cache
-	cache := cache.New(app.cfg.Cache.DefaultExpiration, app.cfg.Cache.CleanupInterval)
-	app.log.Info("launched cache janitor in goroutine")
-
-	// Cache adapter to wire cache keys-value, to domain structs
-	cacheAdapter := adapter.NewAdapter(cache, app.log)
+	// Components that run in the background are started in the order they are appended
+	// and stopped in reverse order on shutdown
+	lc := lifecycle.New(app.log)
 
-	// Usecase (CarStore) - business logic layer
-	carStore := carstore.New(app.log, repo, cacheAdapter)
+	// Repository - Storage layer (WebAPI or local file storage)
+	repo, err := app.newRepository(lc)
+	if err != nil {
+		app.log.Error("failed to init repository", slog.Any("error", err))
+		return e.Wrap("failed to init repository", err)
+	}
 
 	// parse templates
 	templates, err := httpserver.ParseTemplates(app.cfg.HTTPServer.TemplatesPath, app.log)
@@ -67,18 +64,190 @@ func (app *App) Run() error {
 		return e.Wrap("failed to parse templates", err)
 	}
 
+	// Cache - in memory, or a Redis compatible server shared by several viewer instances
+	var (
+		cacheProvider carstore.CacheProvider
+		cacheStats    handlers.CacheStats // only the in-memory cache keeps stats
+	)
+
+	switch app.cfg.Cache.Backend {
+	case config.CacheRedis:
+		client := redis.New(app.cfg.Cache.RedisAddr, app.cfg.Cache.RedisPassword, app.cfg.Cache.RedisDB, time.Second)
+
+		lc.Append(lifecycle.Hook{
+			Name: "redis client",
+			Start: func(ctx context.Context) error {
+				// The cache is optional, so the viewer starts anyway and loads from the repository
+				if err := client.Ping(ctx); err != nil {
+					app.log.Warn("redis is unreachable, serving without cache until it is back", slog.Any("error", err))
+				}
+				return nil
+			},
+			Stop: func(context.Context) error {
+				return client.Close()
+			},
+		})
+
+		app.log.Info("using redis cache", slog.String("addr", app.cfg.Cache.RedisAddr))
+		cacheProvider = adapter.NewRedisAdapter(client, app.cfg.Cache.DefaultExpiration, app.log)
+	default:
+		cacheAdapter := app.newMemoryCache()
+
+		lc.Append(lifecycle.Hook{
+			Name: "cache janitors",
+			Stop: func(ctx context.Context) error {
+				return cacheAdapter.Close(ctx)
+			},
+		})
+
+		// Warm restarts: load the cache saved on the last shutdown and save it again on this one.
+		// Appended after the janitors, so the snapshot is saved first.
+		if dir := app.cfg.Cache.SnapshotDir; dir != "" {
+			lc.Append(lifecycle.Hook{
+				Name: "cache snapshot",
+				Start: func(context.Context) error {
+					cacheAdapter.LoadSnapshot(dir)
+					return nil
+				},
+				Stop: func(context.Context) error {
+					return cacheAdapter.SaveSnapshot(dir)
+				},
+			})
+		}
+
+		cacheProvider = cacheAdapter
+		cacheStats = cacheAdapter
+	}
+
+	// Usecase (CarStore) - business logic layer
+	carStore := carstore.New(app.log, repo, cacheProvider)
+
+	// Background refresher keeps an in-memory snapshot of the whole dataset up to date
+	if app.cfg.Storage.RefreshInterval > 0 {
+		lc.Go("snapshot refresher", func(ctx context.Context) error {
+			carStore.RunRefresher(ctx, app.cfg.Storage.RefreshInterval)
+			return nil
+		})
+	}
+
 	// Router -> Transport layer
-	router := httpserver.NewRouter(app.log, templates, carStore)
+	if !app.cfg.HTTPServer.DebugEndpoints {
+		cacheStats = nil
+	}
+
+	router := httpserver.NewRouter(app.log, templates, carStore, app.mediaPath(), cacheStats)
 
 	// Server
 	// TODO: maybe move to pkg as well.
 	httpServer := httpserver.NewHTTPServer(router, app.cfg)
 
-	// run server
-	if err := httpserver.RunServer(appCtx, app.log, app.cfg, httpServer, 5*time.Second); err != nil {
-		app.log.Error("failed to start server", slog.Any("error", err))
+	// Started last, so it's the first to stop and no request hits a stopped component
+	lc.Append(httpserver.ServerHook(app.log, httpServer, lc.Fail))
+
+	if err := lc.Start(context.Background()); err != nil {
+		app.log.Error("failed to start", slog.Any("error", err))
 		return err
 	}
 
-	return nil
+	// Run until a shutdown signal or a component failure
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var runErr error
+
+	select {
+	case <-sigCtx.Done():
+		app.log.Info("shutdown signal received, starting graceful shutdown")
+	case runErr = <-lc.Failed():
+		app.log.Error("component failed, shutting down", slog.Any("error", runErr))
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := lc.Stop(stopCtx); err != nil {
+		app.log.Error("some components failed to stop", slog.Any("error", err))
+		return errors.Join(runErr, err)
+	}
+
+	app.log.Info("shutdown complete gracefully")
+
+	return runErr
+}
+
+// newMemoryCache creates the in-memory caches, one typed cache per kind of value,
+// and the adapter that wires cache keys to domain structs.
+func (app *App) newMemoryCache() *adapter.CacheAdapter {
+	carsCache := cache.New(app.cfg.Cache.DefaultExpiration, app.cfg.Cache.CleanupInterval,
+		cache.WithMaxEntries[string, domain.Car](app.cfg.Cache.MaxEntries),
+		cache.WithMaxBytes(app.cfg.Cache.MaxBytes, adapter.CarSize),
+		cache.WithShards[string, domain.Car](app.cfg.Cache.Shards),
+		cache.WithStatsGroups[string, domain.Car](adapter.KeyPrefix),
+		cache.WithStaleWindow[string, domain.Car](app.cfg.Cache.StaleWindow),
+		cache.WithTags(adapter.CarTags),
+	)
+	metadataCache := cache.New(app.cfg.Cache.DefaultExpiration, app.cfg.Cache.CleanupInterval,
+		cache.WithStatsGroups[string, domain.Metadata](adapter.KeyPrefix),
+		cache.WithStaleWindow[string, domain.Metadata](app.cfg.Cache.StaleWindow),
+	)
+	app.log.Info("launched cache janitors in goroutines")
+
+	return adapter.NewAdapter(carsCache, metadataCache, app.log)
+}
+
+// newRepository picks the data source configured in storage.source
+func (app *App) newRepository(lc *lifecycle.Lifecycle) (carstore.CarProvider, error) {
+	switch app.cfg.Storage.Source {
+	case config.SourceFile:
+		app.log.Info("using local file storage", slog.String("path", app.cfg.Storage.DataPath))
+		return jsonfile.New(app.log, app.cfg.Storage.DataPath)
+	default:
+		// Client
+		opts := []httpclient.Option{
+			httpclient.WithRetry(
+				app.cfg.Client.Retries,
+				app.cfg.Client.RetryBaseDelay,
+				app.cfg.Client.RetryMaxDelay,
+			),
+			httpclient.WithCircuitBreaker(
+				app.cfg.Client.BreakerThreshold,
+				app.cfg.Client.BreakerCooldown,
+				func(from, to httpclient.State) {
+					app.log.Warn("car api circuit breaker state changed",
+						slog.String("from", from.String()),
+						slog.String("to", to.String()),
+					)
+				},
+			),
+		}
+
+		if app.cfg.Client.ConditionalRequests {
+			opts = append(opts, httpclient.WithConditionalRequests(app.cfg.Client.ConditionalMaxEntries))
+		}
+
+		if app.cfg.Client.CoalesceRequests {
+			opts = append(opts, httpclient.WithRequestCoalescing())
+		}
+
+		client := httpclient.New(app.cfg.Client.Host, app.cfg.Client.Timeout, opts...)
+
+		lc.Append(lifecycle.Hook{
+			Name: "car api client",
+			Stop: func(context.Context) error {
+				client.CloseIdleConnections()
+				return nil
+			},
+		})
+
+		app.log.Info("using webapi storage", slog.String("host", app.cfg.Client.Host))
+		return webapi.New(app.log, client), nil
+	}
+}
+
+// mediaPath returns the local images directory the viewer has to serve itself, if any.
+func (app *App) mediaPath() string {
+	if app.cfg.Storage.Source == config.SourceFile {
+		return app.cfg.Storage.ImagesPath
+	}
+	return ""
 }
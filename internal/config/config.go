@@ -14,6 +14,7 @@ type Config struct {
 	HTTPServer HTTPServer `json:"http_server"`
 	Client     Client     `json:"client"`
 	Cache      Cache      `json:"cache"`
+	Storage    Storage    `json:"storage"`
 }
 
 type HTTPServer struct {
@@ -24,19 +25,83 @@ type HTTPServer struct {
 	IdleTimeout    time.Duration
 	TimeoutStr     string `json:"timeout"`      // temporary field to parse seconds and convert them later to time.Duration
 	IdleTimeoutStr string `json:"idle_timeout"` // ~//~
+
+	// Serve /debug/* endpoints (cache stats), keep it off in production
+	DebugEndpoints bool `json:"debug_endpoints"`
 }
 
 type Client struct {
 	Host       string `json:"host"`
 	Timeout    time.Duration
 	TimeoutStr string `json:"cleint_timeout"`
+
+	// Retries of failed GETs with jittered exponential backoff, 0 disables retries
+	Retries           int `json:"retries"`
+	RetryBaseDelay    time.Duration
+	RetryMaxDelay     time.Duration
+	RetryBaseDelayStr string `json:"retry_base_delay"`
+	RetryMaxDelayStr  string `json:"retry_max_delay"`
+
+	// Circuit breaker opens after BreakerThreshold failed requests in a row, 0 disables it
+	BreakerThreshold   int `json:"breaker_threshold"`
+	BreakerCooldown    time.Duration
+	BreakerCooldownStr string `json:"breaker_cooldown"`
+
+	// Revalidate responses with ETag / Last-Modified instead of downloading them again
+	ConditionalRequests bool `json:"conditional_requests"`
+	// URLs to keep validators and bodies for, least recently requested are dropped first. 0 means 1000.
+	ConditionalMaxEntries int `json:"conditional_max_entries"`
+
+	// Concurrent requests for the same URL share one upstream call
+	CoalesceRequests bool `json:"coalesce_requests"`
 }
 
+// Cache backends
+const (
+	CacheMemory = "memory" // pkg/cache inside the viewer process
+	CacheRedis  = "redis"  // a Redis compatible server, shared by several instances
+)
+
 type Cache struct {
+	Backend string `json:"backend"`
+
+	// Used only with the "redis" backend
+	RedisAddr     string `json:"redis_addr"`
+	RedisPassword string `json:"redis_password"`
+	RedisDB       int    `json:"redis_db"`
+
 	DefaultExpiration    time.Duration
 	CleanupInterval      time.Duration
 	DefaultExpirationStr string `json:"default_expiration"`
 	CleanupIntervalStr   string `json:"cleanup_interval"`
+
+	// Expired items are still served for StaleWindow while one background load refreshes them, 0 disables it
+	StaleWindow    time.Duration
+	StaleWindowStr string `json:"stale_window"`
+
+	// Capacity of the cars cache, least recently used cars are evicted first. 0 means no limit.
+	MaxEntries int   `json:"max_entries"`
+	MaxBytes   int64 `json:"max_bytes"` // approximate
+
+	// Number of independently locked shards of the cars cache, 0 or 1 means a single lock
+	Shards int `json:"shards"`
+
+	// Directory to save the cache to on shutdown and load it from on start, empty disables it
+	SnapshotDir string `json:"snapshot_dir"`
+}
+
+// Data sources for the repository layer
+const (
+	SourceWebAPI = "webapi" // carapi (Node.js) over HTTP
+	SourceFile   = "file"   // carapi/data.json and carapi/img read directly from disk
+)
+
+type Storage struct {
+	Source             string `json:"source"`
+	DataPath           string `json:"data_path"`   // used only with the "file" source
+	ImagesPath         string `json:"images_path"` // ~//~
+	RefreshInterval    time.Duration
+	RefreshIntervalStr string `json:"refresh_interval"` // 0 or empty disables the background refresher
 }
 
 func MustLoad() *Config {
@@ -78,6 +143,25 @@ func MustLoad() *Config {
 		log.Fatalf("can't parse client timeout: %v", err)
 	}
 
+	if cfg.Client.Retries > 0 {
+		cfg.Client.RetryBaseDelay, err = time.ParseDuration(cfg.Client.RetryBaseDelayStr)
+		if err != nil {
+			log.Fatalf("can't parse client retry base delay: %v", err)
+		}
+
+		cfg.Client.RetryMaxDelay, err = time.ParseDuration(cfg.Client.RetryMaxDelayStr)
+		if err != nil {
+			log.Fatalf("can't parse client retry max delay: %v", err)
+		}
+	}
+
+	if cfg.Client.BreakerThreshold > 0 {
+		cfg.Client.BreakerCooldown, err = time.ParseDuration(cfg.Client.BreakerCooldownStr)
+		if err != nil {
+			log.Fatalf("can't parse client breaker cooldown: %v", err)
+		}
+	}
+
 	cfg.Cache.DefaultExpiration, err = time.ParseDuration(cfg.Cache.DefaultExpirationStr)
 	if err != nil {
 		log.Fatalf("can't parse cache default expiration: %v", err)
@@ -88,6 +172,47 @@ func MustLoad() *Config {
 		log.Fatalf("can't parse cache cleanup interval: %v", err)
 	}
 
+	if cfg.Cache.StaleWindowStr != "" {
+		cfg.Cache.StaleWindow, err = time.ParseDuration(cfg.Cache.StaleWindowStr)
+		if err != nil {
+			log.Fatalf("can't parse cache stale window: %v", err)
+		}
+	}
+
+	if cfg.Storage.RefreshIntervalStr != "" {
+		cfg.Storage.RefreshInterval, err = time.ParseDuration(cfg.Storage.RefreshIntervalStr)
+		if err != nil {
+			log.Fatalf("can't parse storage refresh interval: %v", err)
+		}
+	}
+
+	switch cfg.Cache.Backend {
+	case "":
+		cfg.Cache.Backend = CacheMemory
+	case CacheMemory:
+	case CacheRedis:
+		if cfg.Cache.RedisAddr == "" {
+			log.Fatalf("cache redis_addr is required for the redis backend")
+		}
+	default:
+		log.Fatalf("unknown cache backend: %s", cfg.Cache.Backend)
+	}
+
+	switch cfg.Storage.Source {
+	case "":
+		cfg.Storage.Source = SourceWebAPI
+	case SourceWebAPI:
+	case SourceFile:
+		if _, err := os.Stat(cfg.Storage.DataPath); err != nil {
+			log.Fatalf("can't find storage data file: %v", err)
+		}
+		if err := loadStatic(cfg.Storage.ImagesPath); err != nil {
+			log.Fatalf("can't load storage images directory: %v", err)
+		}
+	default:
+		log.Fatalf("unknown storage source: %s", cfg.Storage.Source)
+	}
+
 	return &cfg
 }
 
@@ -66,7 +66,7 @@ func (h *CarHandler) Index(w http.ResponseWriter, r *http.Request) {
 	car, err := h.uc.Car(ctx, ID)
 	if err != nil {
 		log.Warn("car not found", "id", ID, slog.Any("error", err))
-		RenderError(w, h.tmplts, log, http.StatusNotFound)
+		RenderError(w, h.tmplts, log, errorStatus(err))
 		return
 	}
 
@@ -81,7 +81,7 @@ func (h *CarHandler) Index(w http.ResponseWriter, r *http.Request) {
 	recommendedCars, err := h.uc.RecommendedCars(ctx, viewedCarIDs, ID)
 	if err != nil {
 		log.Error("failed to load recommended cars", slog.Any("error", err))
-		RenderError(w, h.tmplts, log, http.StatusInternalServerError)
+		RenderError(w, h.tmplts, log, errorStatus(err))
 		return
 	}
 
@@ -5,7 +5,10 @@ import (
 	"context"
 	"html/template"
 	"log/slog"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -13,7 +16,7 @@ import (
 )
 
 type CatalogUsecase interface {
-	Catalog(ctx context.Context, filters domain.FilterOptions) ([]domain.Car, error)
+	Catalog(ctx context.Context, filters domain.FilterOptions) (domain.CatalogPage, error)
 	Metadata(ctx context.Context) (domain.Metadata, error)
 	// We expect a new method that accepts filters
 }
@@ -40,17 +43,35 @@ func (h *CatalogHandler) Index(w http.ResponseWriter, r *http.Request) {
 	// Parse FilterOptions
 	q := r.URL.Query()
 
+	// Multi-select filters come as repeated params, e.g. ?manufacturer_id=1&manufacturer_id=3
 	filters := domain.FilterOptions{
-		Transmission: q.Get("transmission"),
-		Drivetrain:   q.Get("drivetrain"),
-		SearchQuery:  q.Get("q"),
+		ManufacturerIDs: intValues(q["manufacturer_id"]),
+		CategoryIDs:     intValues(q["category_id"]),
+		Transmissions:   stringValues(q["transmission"]),
+		Drivetrains:     stringValues(q["drivetrain"]),
+		SearchQuery:     q.Get("q"),
 	}
 
 	// Helper to safely parse integers (defaults to 0 if empty/invalid)
-	filters.ManufacturerID, _ = strconv.Atoi(q.Get("manufacturer_id"))
-	filters.CategoryID, _ = strconv.Atoi(q.Get("category_id"))
 	filters.MinYear, _ = strconv.Atoi(q.Get("min_year"))
+	filters.MaxYear, _ = strconv.Atoi(q.Get("max_year"))
 	filters.MinHP, _ = strconv.Atoi(q.Get("min_hp"))
+	filters.MaxHP, _ = strconv.Atoi(q.Get("max_hp"))
+
+	// Sorting, e.g. ?sort=-hp,name
+	filters.Sort = parseSort(q.Get("sort"))
+	sort := formatSort(filters.Sort)
+
+	// Links built with replaceParam carry the cleaned up sort
+	if sort != "" {
+		q.Set("sort", sort)
+	} else {
+		q.Del("sort")
+	}
+
+	// Pagination, out of range values are clamped by the usecase
+	filters.Page, _ = strconv.Atoi(q.Get("page"))
+	filters.PerPage, _ = strconv.Atoi(q.Get("per_page"))
 
 	// Comparisson logic
 	compareIDsStr := q.Get("compare_ids")
@@ -73,13 +94,26 @@ func (h *CatalogHandler) Index(w http.ResponseWriter, r *http.Request) {
 	limitReached := count >= 3
 
 	// Fetch Data (Cars & Metadata for Dropdowns)
-	cars, err := h.uc.Catalog(ctx, filters)
+	page, err := h.uc.Catalog(ctx, filters)
 	if err != nil {
 		log.Error("failed to load catalog", slog.Any("error", err))
-		RenderError(w, h.tmplts, log, http.StatusInternalServerError)
+		RenderError(w, h.tmplts, log, errorStatus(err))
 		return
 	}
 
+	// Links keep the page that is actually shown, and the page size only if it was asked for
+	if p := pageParam(page.Page); p != "" {
+		q.Set("page", p)
+	} else {
+		q.Del("page")
+	}
+
+	if filters.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(page.PerPage))
+	} else {
+		q.Del("per_page")
+	}
+
 	// Load to display filters in a sidebar
 	metadata, err := h.uc.Metadata(ctx)
 	if err != nil {
@@ -89,14 +123,23 @@ func (h *CatalogHandler) Index(w http.ResponseWriter, r *http.Request) {
 
 	// 3. Render
 	data := map[string]any{
-		"Title":        "Catalog | RedCar Oy",
-		"Cars":         cars,
-		"Metadata":     metadata,
-		"Filters":      filters,       // Pass back so we can "pre-fill" the form inputs
-		"CompareIDs":   compareIDsStr, // The raw string for generating links
-		"SelectedMap":  selectedMap,   // To visually mark selected cars
-		"LimitReached": limitReached,  // To block add for comparisson button
-		"Params":       r.URL.Query(),
+		"Title":         "Catalog | RedCar Oy",
+		"Cars":          page.Cars,
+		"Page":          page,
+		"Facets":        page.Facets,
+		"Query":         filters.SearchQuery,
+		"SuggestionURL": suggestionURL(q, page.Suggestion),
+		"QueryError":    page.QueryError,
+		"QueryCaret":    queryCaret(page.QueryError),
+		"Pager":         newPager(page),
+		"Metadata":      metadata,
+		"Filters":       filters,       // Pass back so we can "pre-fill" the form inputs
+		"CompareIDs":    compareIDsStr, // The raw string for generating links
+		"SelectedMap":   selectedMap,   // To visually mark selected cars
+		"LimitReached":  limitReached,  // To block add for comparisson button
+		"Params":        q,
+		"Sort":          sort,
+		"SortOptions":   sortOptionsFor(sort),
 	}
 
 	tmpl, ok := h.tmplts["catalog.html"]
@@ -116,3 +159,51 @@ func (h *CatalogHandler) Index(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	buf.WriteTo(w)
 }
+
+// suggestionURL links to the catalog with the suggested query instead of the current one,
+// from the first page
+func suggestionURL(q url.Values, suggestion string) string {
+	if suggestion == "" {
+		return ""
+	}
+
+	params := maps.Clone(q)
+	params.Set("q", suggestion)
+	params.Del("page")
+
+	return "/catalog?" + params.Encode()
+}
+
+// queryCaret points at the error position under the query, shown in a monospace block
+func queryCaret(qe *domain.QueryError) string {
+	if qe == nil {
+		return ""
+	}
+	return strings.Repeat(" ", qe.Pos) + "^"
+}
+
+// intValues parses repeated integer params, skipping empty, invalid and duplicate values
+func intValues(raw []string) []int {
+	var ids []int
+	for _, v := range raw {
+		id, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || id <= 0 || slices.Contains(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// stringValues drops empty and duplicate values of repeated params
+func stringValues(raw []string) []string {
+	var vals []string
+	for _, v := range raw {
+		v = strings.TrimSpace(v)
+		if v == "" || slices.Contains(vals, v) {
+			continue
+		}
+		vals = append(vals, v)
+	}
+	return vals
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+type CacheStats interface {
+	Stats() map[string]domain.CacheStats
+}
+
+// DebugHandler exposes internals that help to tune the viewer, e.g. the cache counters.
+type DebugHandler struct {
+	log   *slog.Logger
+	cache CacheStats
+}
+
+func NewDebugHandler(log *slog.Logger, cache CacheStats) *DebugHandler {
+	return &DebugHandler{log: log, cache: cache}
+}
+
+type cacheStatsJSON struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	StaleHits   uint64 `json:"stale_hits"`
+	Sets        uint64 `json:"sets"`
+	Expirations uint64 `json:"expirations"`
+	Evictions   uint64 `json:"evictions"`
+	Invalidated uint64 `json:"invalidated"`
+	Items       int    `json:"items"`
+}
+
+// Cache writes the cache stats per key prefix as JSON.
+func (h *DebugHandler) Cache(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.debug.Cache"
+
+	log := h.log.With("op", op)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	resp := make(map[string]cacheStatsJSON)
+	for prefix, s := range h.cache.Stats() {
+		resp[prefix] = cacheStatsJSON(s)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(resp); err != nil {
+		log.Error("failed to encode cache stats", slog.Any("error", err))
+	}
+}
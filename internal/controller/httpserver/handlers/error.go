@@ -2,11 +2,28 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 )
 
+// errorStatus picks the error page for a failed usecase call by the domain error it wraps.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable // degraded mode, the car API is down
+	case errors.Is(err, domain.ErrInvalidData):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func RenderError(w http.ResponseWriter, tmplts map[string]*template.Template, log *slog.Logger, code int) {
 	const op = "handlers.common.RenderError"
 
@@ -28,6 +45,22 @@ func RenderError(w http.ResponseWriter, tmplts map[string]*template.Template, lo
 		message = "We are currently experiencing technical difficulties. Our mechanics are working on it."
 	}
 
+	// Degraded mode: the car API is down and the circuit breaker fails requests fast
+	if code == http.StatusServiceUnavailable {
+		tmplName = "maintenance.html"
+		title = "Service Unavailable | RedCars"
+		heading = "Temporarily Unavailable"
+		message = "Our car database is not responding right now. Please try again in a few moments."
+	}
+
+	// The car API answered, but with data we can't use
+	if code == http.StatusBadGateway {
+		tmplName = "maintenance.html"
+		title = "Bad Gateway | RedCars"
+		heading = "Bad Gateway"
+		message = "We received unexpected data from our car database. Our mechanics are working on it."
+	}
+
 	data := map[string]any{
 		"Title":   title,
 		"Heading": heading,
@@ -47,7 +47,7 @@ func (h *HomeHandler) Index(w http.ResponseWriter, r *http.Request) {
 	recommendedCars, err := h.uc.RecommendedCars(ctx, viewedCarIDs, 0)
 	if err != nil {
 		log.Error("failed to load recommended cars", slog.Any("error", err))
-		RenderError(w, h.tmplts, log, http.StatusInternalServerError)
+		RenderError(w, h.tmplts, log, errorStatus(err))
 		return
 	}
 
@@ -55,7 +55,7 @@ func (h *HomeHandler) Index(w http.ResponseWriter, r *http.Request) {
 	popularCars, err := h.uc.RandomCars(ctx)
 	if err != nil {
 		log.Error("failed to load home data", slog.Any("error", err))
-		RenderError(w, h.tmplts, log, http.StatusInternalServerError)
+		RenderError(w, h.tmplts, log, errorStatus(err))
 		return
 	}
 
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"strconv"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// How many page numbers to show on each side of the current page
+const pagerRadius = 2
+
+// pageLink is one entry of the pagination bar. Number 0 is a gap ("...").
+type pageLink struct {
+	Number  int
+	Param   string // page= value, empty for the first page to keep URLs short
+	Current bool
+}
+
+// pager is what catalog.html needs to render the pagination bar
+type pager struct {
+	Prev, Next *pageLink // nil on the first / last page
+	Links      []pageLink
+	From, To   int // 1-based positions of the shown cars, e.g. 13-24 of 50
+}
+
+func newPager(p domain.CatalogPage) pager {
+	var pg pager
+
+	if len(p.Cars) > 0 {
+		pg.From = (p.Page-1)*p.PerPage + 1
+		pg.To = pg.From + len(p.Cars) - 1
+	}
+
+	if p.Pages <= 1 {
+		return pg
+	}
+
+	if p.Page > 1 {
+		pg.Prev = &pageLink{Number: p.Page - 1, Param: pageParam(p.Page - 1)}
+	}
+	if p.Page < p.Pages {
+		pg.Next = &pageLink{Number: p.Page + 1, Param: pageParam(p.Page + 1)}
+	}
+
+	// First, last and the pages around the current one, with gaps in between
+	last := 0
+	for n := 1; n <= p.Pages; n++ {
+		if n != 1 && n != p.Pages && (n < p.Page-pagerRadius || n > p.Page+pagerRadius) {
+			continue
+		}
+		if n > last+1 {
+			pg.Links = append(pg.Links, pageLink{})
+		}
+		pg.Links = append(pg.Links, pageLink{Number: n, Param: pageParam(n), Current: n == p.Page})
+		last = n
+	}
+
+	return pg
+}
+
+func pageParam(n int) string {
+	if n <= 1 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
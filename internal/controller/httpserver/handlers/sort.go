@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// sortOption is one entry of the sort dropdown in the catalog sidebar
+type sortOption struct {
+	Value string // the sort= query parameter
+	Label string
+}
+
+// Presets offered in the sidebar. Any other combination still works through the URL, e.g. ?sort=category,-hp,name
+var sortOptions = []sortOption{
+	{"", "Default"},
+	{"-hp", "Power: high to low"},
+	{"hp", "Power: low to high"},
+	{"-year", "Year: newest first"},
+	{"year", "Year: oldest first"},
+	{"name", "Name: A to Z"},
+	{"-name", "Name: Z to A"},
+	{"manufacturer,name", "Manufacturer"},
+	{"category,-hp", "Body type"},
+}
+
+// parseSort reads a comma separated list of sort fields, e.g. "-hp,name".
+// A leading "-" means descending. Unknown and repeated fields are skipped.
+func parseSort(raw string) []domain.SortKey {
+	var keys []domain.SortKey
+	seen := make(map[string]bool)
+
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+
+		key := domain.SortKey{Field: p}
+		if f, ok := strings.CutPrefix(p, "-"); ok {
+			key = domain.SortKey{Field: f, Desc: true}
+		}
+
+		switch key.Field {
+		case domain.SortHP, domain.SortYear, domain.SortName, domain.SortManufacturer, domain.SortCategory:
+		default:
+			continue
+		}
+
+		if seen[key.Field] {
+			continue
+		}
+		seen[key.Field] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// formatSort is the reverse of parseSort, so links always carry a clean sort= value
+func formatSort(keys []domain.SortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.Desc {
+			parts = append(parts, "-"+k.Field)
+		} else {
+			parts = append(parts, k.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortOptionsFor returns the dropdown entries, plus the current sort if it came from a hand-written URL
+func sortOptionsFor(current string) []sortOption {
+	for _, o := range sortOptions {
+		if o.Value == current {
+			return sortOptions
+		}
+	}
+	return append(slices.Clip(sortOptions), sortOption{current, "Custom (" + current + ")"})
+}
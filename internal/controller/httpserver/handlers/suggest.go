@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+type SuggestUsecase interface {
+	Suggest(ctx context.Context, query string, limit int) ([]domain.Suggestion, error)
+}
+
+// How many suggestions the search box shows, and the longest query it looks up (same as the input's maxlength)
+const (
+	suggestLimit    = 8
+	suggestMaxQuery = 50
+)
+
+// SuggestHandler serves autocomplete for the header search box as JSON
+type SuggestHandler struct {
+	log *slog.Logger
+	uc  SuggestUsecase
+}
+
+func NewSuggestHandler(log *slog.Logger, uc SuggestUsecase) *SuggestHandler {
+	return &SuggestHandler{log: log, uc: uc}
+}
+
+type suggestResponse struct {
+	Query       string           `json:"query"`
+	Suggestions []suggestionJSON `json:"suggestions"`
+}
+
+type suggestionJSON struct {
+	Type string `json:"type"` // car, manufacturer or category
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Suggest answers GET /api/suggest?q=bm with the ranked completions of the query
+func (h *SuggestHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.suggest.Suggest"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	query := []rune(r.URL.Query().Get("q"))
+	if len(query) > suggestMaxQuery {
+		query = query[:suggestMaxQuery]
+	}
+
+	resp := suggestResponse{
+		Query:       string(query),
+		Suggestions: []suggestionJSON{}, // [] rather than null for the script
+	}
+
+	suggestions, err := h.uc.Suggest(r.Context(), resp.Query, suggestLimit)
+	if err != nil {
+		log.Error("failed to get suggestions", slog.Any("error", err))
+		writeJSON(w, log, errorStatus(err), map[string]string{"error": http.StatusText(errorStatus(err))})
+		return
+	}
+
+	for _, s := range suggestions {
+		resp.Suggestions = append(resp.Suggestions, suggestionJSON{
+			Type: s.Type,
+			Text: s.Text,
+			URL:  suggestionTarget(s),
+		})
+	}
+
+	// The catalog changes rarely, let the browser reuse answers while the user retypes
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, log, http.StatusOK, resp)
+}
+
+// suggestionTarget is where picking a suggestion leads: the car page or the filtered catalog
+func suggestionTarget(s domain.Suggestion) string {
+	switch s.Type {
+	case domain.SuggestManufacturer:
+		return fmt.Sprintf("/catalog?manufacturer_id=%d", s.ID)
+	case domain.SuggestCategory:
+		return fmt.Sprintf("/catalog?category_id=%d", s.ID)
+	default:
+		return fmt.Sprintf("/catalog/%d", s.ID)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, log *slog.Logger, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("failed to encode json", slog.Any("error", err))
+	}
+}
@@ -17,11 +17,14 @@ type CarStorage interface {
 	Cars(ctx context.Context) ([]domain.Car, error)
 	RandomCars(ctx context.Context) ([]domain.Car, error)
 	RecommendedCars(ctx context.Context, IDs []int, excID int) ([]domain.Car, error)
-	Catalog(ctx context.Context, filters domain.FilterOptions) ([]domain.Car, error)
+	Catalog(ctx context.Context, filters domain.FilterOptions) (domain.CatalogPage, error)
 	Metadata(ctx context.Context) (domain.Metadata, error)
+	Suggest(ctx context.Context, query string, limit int) ([]domain.Suggestion, error)
 }
 
-func NewRouter(log *slog.Logger, tmplts map[string]*template.Template, storage CarStorage) http.Handler {
+// mediaPath is a local images directory served under /media/, empty when images come from the webapi.
+// cacheStats is served under /debug/cache, nil disables the debug endpoints.
+func NewRouter(log *slog.Logger, tmplts map[string]*template.Template, storage CarStorage, mediaPath string, cacheStats handlers.CacheStats) http.Handler {
 	mux := http.NewServeMux()
 
 	addRoutes(
@@ -29,6 +32,8 @@ func NewRouter(log *slog.Logger, tmplts map[string]*template.Template, storage C
 		log,
 		tmplts,
 		storage,
+		mediaPath,
+		cacheStats,
 	)
 
 	reqID := middleware.NewReqIDMiddleware(log)
@@ -40,24 +45,38 @@ func NewRouter(log *slog.Logger, tmplts map[string]*template.Template, storage C
 
 // func newMiddleware(log *slog.Logger) func(h http.Handler) http.Handler
 
-func addRoutes(mux *http.ServeMux, logger *slog.Logger, tmplts map[string]*template.Template, storage CarStorage) {
+func addRoutes(mux *http.ServeMux, logger *slog.Logger, tmplts map[string]*template.Template, storage CarStorage, mediaPath string, cacheStats handlers.CacheStats) {
 
 	homeHandler := handlers.NewHomeHandler(logger, tmplts, storage)
 	carHandler := handlers.NewCarHandler(logger, tmplts, storage)
 	catalogHandler := handlers.NewCatalogHandler(logger, tmplts, storage)
 	notFoundHandler := handlers.NewNotFoundHandler(logger, tmplts)
 	compareHandler := handlers.NewCompareHandler(logger, tmplts, storage)
+	suggestHandler := handlers.NewSuggestHandler(logger, storage)
 
 	mux.HandleFunc("GET /{$}", homeHandler.Index)
 	mux.HandleFunc("GET /catalog/{id}", carHandler.Index)
 	mux.HandleFunc("GET /catalog", catalogHandler.Index)
 	mux.HandleFunc("GET /compare", compareHandler.Index)
+	mux.HandleFunc("GET /api/suggest", suggestHandler.Suggest)
 	mux.HandleFunc("/", notFoundHandler.NotFound)
 
 	// Load static
 	fs := http.FileServer(http.Dir("./static"))
 	mux.Handle("GET /static/", http.StripPrefix("/static/", fs))
 
+	// Load car images, when they are not served by the webapi
+	if mediaPath != "" {
+		media := http.FileServer(http.Dir(mediaPath))
+		mux.Handle("GET /media/", http.StripPrefix("/media/", media))
+	}
+
+	// Debug endpoints, for tuning the cache from real data
+	if cacheStats != nil {
+		debugHandler := handlers.NewDebugHandler(logger, cacheStats)
+		mux.HandleFunc("GET /debug/cache", debugHandler.Cache)
+	}
+
 	// Action handlers
 	// mux.Handle("POST /encoder", handlers.HandleEncoder(logger, proc, tmplts))
 }
@@ -5,12 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/config"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/lifecycle"
 )
 
 func NewHTTPServer(handler http.Handler, cfg *config.Config) *http.Server {
@@ -23,46 +22,48 @@ func NewHTTPServer(handler http.Handler, cfg *config.Config) *http.Server {
 	}
 }
 
-func RunServer(ctx context.Context, log *slog.Logger, cfg *config.Config, server *http.Server, shutdownTimeout time.Duration) error {
-	const op = "internal.httpserver.RunServer"
+// ServerHook runs server as a lifecycle component. Start binds the address right away,
+// so a busy port fails the startup, and then serves in the background.
+// Serving errors are reported through fail. Stop shuts the server down gracefully
+// and closes the remaining connections if ctx runs out first.
+func ServerHook(log *slog.Logger, server *http.Server, fail func(error)) lifecycle.Hook {
+	const op = "internal.httpserver.ServerHook"
 
 	log = log.With(
 		slog.String("op", op),
 	)
 
-	shutdownCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	return lifecycle.Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			var lc net.ListenConfig
+			ln, err := lc.Listen(ctx, "tcp", server.Addr)
+			if err != nil {
+				log.Error("failed to start server", slog.Any("error", err))
+				return fmt.Errorf("server failed to start: %w", err)
+			}
 
-	// Creating channel to capture only startup errors
-	serverErr := make(chan error, 1)
+			log.Info("starting server", slog.String("address", server.Addr))
 
-	go func() {
-		log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
+			go func() {
+				if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					log.Error("server failed", slog.Any("error", err))
+					fail(fmt.Errorf("server failed: %w", err))
+				}
+			}()
 
-		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			log.Error("failed to start server", slog.Any("error", err))
-			serverErr <- err
-		}
-		close(serverErr)
-	}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := server.Shutdown(ctx); err != nil {
+				log.Error("graceful shutdown timed out, forcing close", slog.Any("error", err))
+				server.Close()
+				return err
+			}
 
-	select {
-	case err := <-serverErr:
-		return fmt.Errorf("server failed to start: %w", err)
-	case <-shutdownCtx.Done():
-		log.Info("shutdown signal received, starting graceful shutdown")
-	}
-
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-	defer cancel()
-
-	if err := server.Shutdown(timeoutCtx); err != nil {
-		log.Error("graceful shutdown timed out, forcing close", slog.Any("error", err))
+			log.Info("server shut down gracefully")
 
-		return server.Close()
+			return nil
+		},
 	}
-
-	log.Info("shutdown complete gracefully")
-
-	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/url"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
@@ -35,6 +36,8 @@ func ParseTemplates(rootDir string, log *slog.Logger) (map[string]*template.Temp
 		"dict":         dict,
 		"replaceParam": replaceParam,
 		"toggleID":     toggleID,
+		"hasInt":       hasInt,
+		"hasString":    hasString,
 		// Add more helpers here if needed later
 	}
 
@@ -142,6 +145,16 @@ func replaceParam(current url.Values, key, value string) string {
 	return "?" + strings.ReplaceAll(encoded, "%2C", ",")
 }
 
+// hasInt and hasString tell if a value is selected in a multi-value filter.
+// Usage: {{if hasInt $.Filters.ManufacturerIDs .ID}}checked{{end}}
+func hasInt(list []int, v int) bool {
+	return slices.Contains(list, v)
+}
+
+func hasString(list []string, v string) bool {
+	return slices.Contains(list, v)
+}
+
 // toggleID adds an ID to a comma-separated list if missing, or removes it if present.
 // Used for the "Add/Remove" logic in the comparison feature.
 func toggleID(currentList string, id int) string {
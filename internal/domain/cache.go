@@ -0,0 +1,13 @@
+package domain
+
+// CacheStats are the counters of one group of cached keys, e.g. every car.
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	StaleHits   uint64 // served while a background load refreshes them
+	Sets        uint64
+	Expirations uint64
+	Evictions   uint64 // removed to stay within capacity
+	Invalidated uint64 // removed because a manufacturer or category changed
+	Items       int
+}
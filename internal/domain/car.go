@@ -1,10 +1,32 @@
 package domain
 
+import "strings"
+
 const (
 	TransmissionManual    = "Manual"
 	TransmissionAutomatic = "Automatic"
 )
 
+// NormalizeGearbox maps a gearbox description from the data source to a transmission.
+// Shared by all repositories, so the transmission filter works the same for any source.
+func NormalizeGearbox(gearbox string) string {
+
+	// If it contains "manual", it's a manual
+	if strings.Contains(strings.ToLower(gearbox), "manual") {
+		return TransmissionManual
+	}
+
+	// Everything else (Automatic, CVT, DSG, Dual Clutch, Single-Speed)
+	// counts as "Automatic" for a general filter.
+	return TransmissionAutomatic
+}
+
+const (
+	DrivetrainAWD = "All-Wheel Drive"
+	DrivetrainFWD = "Front-Wheel Drive"
+	DrivetrainRWD = "Rear-Wheel Drive"
+)
+
 type Car struct {
 	ID           int
 	Name         string
@@ -36,14 +58,66 @@ type Category struct {
 }
 
 // used for user input in catalog to filter cars
+// A car has to match every field that is set, and any of the values within a field.
 type FilterOptions struct {
-	ManufacturerID int
-	CategoryID     int
-	MinYear        int
-	MinHP          int
-	Transmission   string
-	Drivetrain     string
-	SearchQuery    string
+	ManufacturerIDs []int
+	CategoryIDs     []int
+	Transmissions   []string
+	Drivetrains     []string
+
+	// Inclusive ranges, 0 means no bound
+	MinYear int
+	MaxYear int
+	MinHP   int
+	MaxHP   int
+
+	SearchQuery string
+	Sort        []SortKey // applied in order, ties are broken by car ID
+
+	// Pagination, 1-based. 0 means the first page and the default page size
+	Page    int
+	PerPage int
+}
+
+// Catalog sort fields
+const (
+	SortHP           = "hp"
+	SortYear         = "year"
+	SortName         = "name"
+	SortManufacturer = "manufacturer"
+	SortCategory     = "category"
+)
+
+type SortKey struct {
+	Field string // one of the Sort* constants
+	Desc  bool
+}
+
+// One page of the filtered catalog
+type CatalogPage struct {
+	Cars    []Car
+	Total   int // cars matching the filters, on all pages
+	Page    int // clamped to 1..Pages
+	PerPage int
+	Pages   int // at least 1, even when nothing matches
+
+	Facets Facets
+
+	// Corrected search query when the one asked for had no exact hits, "did you mean"
+	Suggestion string
+
+	// Set when the search query has a syntax error, the query is ignored then
+	QueryError *QueryError
+}
+
+// Facets count the matching cars for each filter option. The count of an option
+// applies every active filter except the one of its own field, so it tells how many
+// cars there would be with that option ticked as well.
+type Facets struct {
+	Manufacturers map[int]int // manufacturer ID -> cars
+	Categories    map[int]int // category ID -> cars
+	Transmissions map[string]int
+	Drivetrains   map[string]int
 }
 
 type Metadata struct {
@@ -52,3 +126,24 @@ type Metadata struct {
 	Drivetrains   []string // e.g. "All-Wheel Drive", "Rear-Wheel Drive", "Front-Wheel Drive"
 	Transmissions []string // e.g. "Automatic", "Manual"
 }
+
+// Dataset is the whole catalog as provided by a repository in one pull.
+type Dataset struct {
+	Cars          []Car
+	Manufacturers []Manufacturer
+	Categories    []Category
+}
+
+// Suggestion types
+const (
+	SuggestCar          = "car"
+	SuggestManufacturer = "manufacturer"
+	SuggestCategory     = "category"
+)
+
+// Suggestion is one autocomplete entry of the search box
+type Suggestion struct {
+	Type string // one of the Suggest* constants
+	ID   int    // of the car, manufacturer or category
+	Text string
+}
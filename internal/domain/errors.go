@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors shared by all layers. Repositories translate their own failures into these,
+// usecases pass them through wrapped, and handlers pick the HTTP status by them.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrInvalidData         = errors.New("invalid data")
+)
+
+// QueryError is a syntax error in a catalog search query
+type QueryError struct {
+	Query string
+	Pos   int // in runes from the start of the query
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
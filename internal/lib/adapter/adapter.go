@@ -2,6 +2,7 @@ package adapter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -9,91 +10,44 @@ import (
 	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
 )
 
-// Adapter wraps the generic cache to make it type-safe for the domain
+// Adapter wires domain structs to cache keys. Every kind of value has its own typed cache,
+// so reads need no type assertions.
 type CacheAdapter struct {
-	cache *cache.Cache
-	log   *slog.Logger
+	cars     *cache.Cache[string, domain.Car]
+	metadata *cache.Cache[string, domain.Metadata]
+	log      *slog.Logger
 }
 
-func NewAdapter(c *cache.Cache, logger *slog.Logger) *CacheAdapter {
-	return &CacheAdapter{cache: c, log: logger}
+func NewAdapter(cars *cache.Cache[string, domain.Car], metadata *cache.Cache[string, domain.Metadata], logger *slog.Logger) *CacheAdapter {
+	return &CacheAdapter{cars: cars, metadata: metadata, log: logger}
 }
 
-func (a *CacheAdapter) Get(ctx context.Context, id int) (domain.Car, bool) {
-	const op = "repository.adapter.Get"
-
-	log := a.log.With(
-		slog.String("op", op),
-	)
-
-	key := fmt.Sprintf("car:%d", id)
-	val, found := a.cache.Get(key)
-	if !found {
-		log.Debug("car not found in cache",
-			slog.Int("car_id", id),
-		)
-		return domain.Car{}, false
-	}
-
-	// Type assertion: Unbox the interface{} back to a Car struct
-	car, ok := val.(domain.Car)
-	if !ok {
-		log.Error("failed to assert cached object to car", slog.Any("error", fmt.Errorf("failed to assert object:%v to car", val)))
-		return domain.Car{}, false
-	}
-
-	log.Debug("car loaded from cache",
-		slog.Int("car_id", id),
-	)
-
-	return car, true
+// GetOrLoad returns the cached car, or loads and caches it. Concurrent loads of one car are shared,
+// and a recently expired car is served while it is refreshed in the background.
+func (a *CacheAdapter) GetOrLoad(ctx context.Context, id int, load func(ctx context.Context) (domain.Car, error)) (domain.Car, error) {
+	return a.cars.GetOrLoad(ctx, fmt.Sprintf("car:%d", id), load)
 }
 
-func (a *CacheAdapter) Set(ctx context.Context, car domain.Car) {
-	const op = "repository.adapter.Set"
-
-	log := a.log.With(
-		slog.String("op", op),
-	)
-
-	key := fmt.Sprintf("car:%d", car.ID)
-	a.cache.Set(key, car, cache.DefaultExpiration)
-
-	log.Debug("car added to cache",
-		slog.Int("car_id", car.ID),
-	)
+// GetOrLoadMetadata is GetOrLoad for the catalog metadata.
+func (a *CacheAdapter) GetOrLoadMetadata(ctx context.Context, load func(ctx context.Context) (domain.Metadata, error)) (domain.Metadata, error) {
+	return a.metadata.GetOrLoad(ctx, "metadata", load)
 }
 
-func (a *CacheAdapter) GetMetadata(ctx context.Context) (domain.Metadata, bool) {
-	const op = "repository.adapter.Get"
-
-	log := a.log.With(
-		slog.String("op", op),
-	)
+// Fixed part of a cached car: its int fields, string headers and the cache bookkeeping, roughly
+const carOverhead = 256
 
-	val, found := a.cache.Get("metadata")
-	if !found {
-		log.Debug("metadata not found in cache")
-		return domain.Metadata{}, false
-	}
-	meta, ok := val.(domain.Metadata)
-	if !ok {
-		log.Error("failed to assert cached object to metadata", slog.Any("error", fmt.Errorf("failed to assert object:%v to metadata", val)))
-		return domain.Metadata{}, false
-	}
-
-	return meta, ok
+// CarSize roughly estimates the memory used by a cached car, for the cache byte limit.
+func CarSize(key string, car domain.Car) int64 {
+	return carOverhead + int64(len(key)) +
+		int64(len(car.Name)+len(car.Image)) +
+		int64(len(car.Specs.Engine)+len(car.Specs.Gearbox)+len(car.Specs.Transmission)+len(car.Specs.Drivetrain)) +
+		int64(len(car.Manufacturer.Name)+len(car.Manufacturer.Country)+len(car.Category.Name))
 }
 
-func (a *CacheAdapter) SetMetadata(ctx context.Context, m domain.Metadata) {
-	const op = "repository.adapter.SetMetadata"
-
-	log := a.log.With(
-		slog.String("op", op),
+// Close stops the cache janitors within ctx. The cached values stay readable.
+func (a *CacheAdapter) Close(ctx context.Context) error {
+	return errors.Join(
+		a.cars.StopJanitor(ctx),
+		a.metadata.StopJanitor(ctx),
 	)
-
-	// Maybe use a longer TTL??? default is 10 min
-	a.cache.Set("metadata", m, cache.DefaultExpiration)
-
-	log.Debug("metadata added to cache")
 }
@@ -0,0 +1,266 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/redis"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
+)
+
+// Keys in redis are namespaced, so the viewer can share a server with other apps
+const (
+	redisPrefix      = "viewer:"
+	redisMetadataKey = redisPrefix + "metadata"
+)
+
+// After a failed round trip redis is skipped for this long, so a dead server costs
+// one dial timeout per backoff instead of one per request
+const redisBackoff = 5 * time.Second
+
+// RedisAdapter is a CacheProvider backed by a Redis compatible server, so several
+// viewer instances share one cache. Cars and metadata are stored as JSON.
+// The cache is only an optimisation: when the server is down, values are loaded
+// from the repository and the failure is logged, and redis is left alone for redisBackoff.
+type RedisAdapter struct {
+	client *redis.Client
+	ttl    time.Duration
+	log    *slog.Logger
+
+	cars     singleflight.Group[int, domain.Car] // dedupes loads within this instance
+	metadata singleflight.Group[string, domain.Metadata]
+
+	downUntil atomic.Int64 // unix nanoseconds, redis isn't tried before that
+}
+
+func NewRedisAdapter(client *redis.Client, ttl time.Duration, logger *slog.Logger) *RedisAdapter {
+	return &RedisAdapter{client: client, ttl: ttl, log: logger}
+}
+
+func redisCarKey(id int) string {
+	return fmt.Sprintf("%scar:%d", redisPrefix, id)
+}
+
+func redisTagKey(tag string) string {
+	return redisPrefix + "tag:" + tag
+}
+
+func (a *RedisAdapter) GetOrLoad(ctx context.Context, id int, load func(ctx context.Context) (domain.Car, error)) (domain.Car, error) {
+	const op = "repository.adapter.redis.GetOrLoad"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	key := redisCarKey(id)
+
+	var car domain.Car
+	if a.get(ctx, log, key, &car) {
+		log.Debug("car loaded from cache", slog.Int("car_id", id))
+		return car, nil
+	}
+
+	car, err, _ := a.cars.Do(ctx, id, func(ctx context.Context) (domain.Car, error) {
+		car, err := load(ctx)
+		if err != nil {
+			return domain.Car{}, err
+		}
+
+		a.set(ctx, log, key, car)
+		a.tag(ctx, log, key, CarTags(key, car))
+
+		return car, nil
+	})
+
+	return car, err
+}
+
+func (a *RedisAdapter) GetOrLoadMetadata(ctx context.Context, load func(ctx context.Context) (domain.Metadata, error)) (domain.Metadata, error) {
+	const op = "repository.adapter.redis.GetOrLoadMetadata"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	var meta domain.Metadata
+	if a.get(ctx, log, redisMetadataKey, &meta) {
+		log.Debug("metadata loaded from cache")
+		return meta, nil
+	}
+
+	meta, err, _ := a.metadata.Do(ctx, redisMetadataKey, func(ctx context.Context) (domain.Metadata, error) {
+		meta, err := load(ctx)
+		if err != nil {
+			return domain.Metadata{}, err
+		}
+
+		a.set(ctx, log, redisMetadataKey, meta)
+
+		return meta, nil
+	})
+
+	return meta, err
+}
+
+func (a *RedisAdapter) InvalidateManufacturer(ctx context.Context, id int) {
+	a.invalidate(ctx, manufacturerTag(id))
+}
+
+func (a *RedisAdapter) InvalidateCategory(ctx context.Context, id int) {
+	a.invalidate(ctx, categoryTag(id))
+}
+
+// up reports whether redis is worth a try, it isn't while backing off after a failure
+func (a *RedisAdapter) up() bool {
+	return time.Now().UnixNano() >= a.downUntil.Load()
+}
+
+// failed starts a backoff, unless err is a reply of a working server or the caller gave up
+func (a *RedisAdapter) failed(ctx context.Context, log *slog.Logger, err error) {
+	var serverErr redis.Error
+	if errors.As(err, &serverErr) || ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	if a.downUntil.Swap(now.Add(redisBackoff).UnixNano()) < now.UnixNano() {
+		log.Warn("redis is unreachable, serving without it for a while",
+			slog.Duration("backoff", redisBackoff),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func (a *RedisAdapter) InvalidateCar(ctx context.Context, id int) {
+	a.del(ctx, redisCarKey(id))
+}
+
+func (a *RedisAdapter) InvalidateMetadata(ctx context.Context) {
+	a.del(ctx, redisMetadataKey)
+}
+
+// get decodes the value at key into v, and reports whether it was found.
+func (a *RedisAdapter) get(ctx context.Context, log *slog.Logger, key string, v any) bool {
+	if !a.up() {
+		return false
+	}
+
+	data, err := a.client.Get(ctx, key)
+	if errors.Is(err, redis.ErrNil) {
+		return false
+	}
+	if err != nil {
+		a.failed(ctx, log, err)
+		log.Warn("failed to read from redis", slog.String("key", key), slog.Any("error", err))
+		return false
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Warn("failed to decode cached value", slog.String("key", key), slog.Any("error", err))
+		return false
+	}
+
+	return true
+}
+
+func (a *RedisAdapter) set(ctx context.Context, log *slog.Logger, key string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error("failed to encode value for cache", slog.String("key", key), slog.Any("error", err))
+		return
+	}
+
+	if !a.up() {
+		return
+	}
+
+	if err := a.client.Set(ctx, key, data, a.ttl); err != nil {
+		a.failed(ctx, log, err)
+		log.Warn("failed to write to redis", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// del deletes key, the value is loaded again on the next read
+func (a *RedisAdapter) del(ctx context.Context, key string) {
+	const op = "repository.adapter.redis.del"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if !a.up() {
+		log.Warn("redis is down, cached value not dropped", slog.String("key", key))
+		return
+	}
+
+	if _, err := a.client.Del(ctx, key); err != nil {
+		a.failed(ctx, log, err)
+		log.Warn("failed to delete from redis", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// tag adds key to the set of every tag. The sets expire a bit later than the values,
+// so they don't grow forever, and a set that outlives its keys is harmless.
+func (a *RedisAdapter) tag(ctx context.Context, log *slog.Logger, key string, tags []string) {
+	for _, tag := range tags {
+		if !a.up() {
+			return
+		}
+
+		tagKey := redisTagKey(tag)
+
+		if err := a.client.SAdd(ctx, tagKey, key); err != nil {
+			a.failed(ctx, log, err)
+			log.Warn("failed to tag cached value", slog.String("key", key), slog.String("tag", tag), slog.Any("error", err))
+			continue
+		}
+
+		if a.ttl > 0 {
+			if err := a.client.Expire(ctx, tagKey, 2*a.ttl); err != nil {
+				a.failed(ctx, log, err)
+				log.Warn("failed to set tag ttl", slog.String("tag", tag), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// invalidate drops every value tagged with tag, and the metadata that lists the tagged entity.
+func (a *RedisAdapter) invalidate(ctx context.Context, tag string) {
+	const op = "repository.adapter.redis.invalidate"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if !a.up() {
+		log.Warn("redis is down, tag not invalidated", slog.String("tag", tag))
+		return
+	}
+
+	tagKey := redisTagKey(tag)
+
+	keys, err := a.client.SMembers(ctx, tagKey)
+	if err != nil {
+		a.failed(ctx, log, err)
+		log.Warn("failed to read tag", slog.String("tag", tag), slog.Any("error", err))
+		return
+	}
+
+	n, err := a.client.Del(ctx, append(keys, tagKey, redisMetadataKey)...)
+	if err != nil {
+		a.failed(ctx, log, err)
+		log.Warn("failed to invalidate tag", slog.String("tag", tag), slog.Any("error", err))
+		return
+	}
+
+	log.Debug("tag invalidated in cache",
+		slog.String("tag", tag),
+		slog.Int64("deleted_count", n),
+	)
+}
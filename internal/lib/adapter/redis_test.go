@@ -0,0 +1,385 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/redis"
+)
+
+// fakeRedis is an in-process RESP server that keeps strings and sets in memory,
+// enough of redis for the adapter. With errorReply set it answers every command with it.
+type fakeRedis struct {
+	addr       string
+	errorReply string
+
+	mu       sync.Mutex
+	strings  map[string]string
+	sets     map[string][]string
+	ttls     map[string]string // key -> seconds of the last SET EX / EXPIRE
+	commands atomic.Int32
+}
+
+func newFakeRedis(t *testing.T, errorReply string) *fakeRedis {
+	t.Helper()
+
+	f := &fakeRedis{
+		errorReply: errorReply,
+		strings:    make(map[string]string),
+		sets:       make(map[string][]string),
+		ttls:       make(map[string]string),
+	}
+	f.addr = listen(t, func(nc net.Conn) {
+		r := bufio.NewReader(nc)
+		for {
+			args, err := readCommand(r)
+			if err != nil {
+				return
+			}
+			f.commands.Add(1)
+			if _, err := io.WriteString(nc, f.handle(args)); err != nil {
+				return
+			}
+		}
+	})
+
+	return f
+}
+
+// listen serves every connection with serve until the test ends and returns the address
+func listen(t *testing.T, serve func(nc net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer nc.Close()
+				serve(nc)
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	if f.errorReply != "" {
+		return "-" + f.errorReply + "\r\n"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SET":
+		f.strings[args[1]] = args[2]
+		delete(f.ttls, args[1])
+		if len(args) == 5 && strings.EqualFold(args[3], "EX") {
+			f.ttls[args[1]] = args[4]
+		}
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			_, isString := f.strings[k]
+			_, isSet := f.sets[k]
+			if isString || isSet {
+				n++
+			}
+			delete(f.strings, k)
+			delete(f.sets, k)
+			delete(f.ttls, k)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SADD":
+		for _, m := range args[2:] {
+			if !slices.Contains(f.sets[args[1]], m) {
+				f.sets[args[1]] = append(f.sets[args[1]], m)
+			}
+		}
+		return ":1\r\n"
+	case "SMEMBERS":
+		members := f.sets[args[1]]
+		reply := fmt.Sprintf("*%d\r\n", len(members))
+		for _, m := range members {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(m), m)
+		}
+		return reply
+	case "EXPIRE":
+		f.ttls[args[1]] = args[2]
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// keys returns the sorted keys of all strings and sets
+func (f *fakeRedis) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for k := range f.strings {
+		keys = append(keys, k)
+	}
+	for k := range f.sets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func newTestRedisAdapter(t *testing.T, addr string, ttl time.Duration) *RedisAdapter {
+	client := redis.New(addr, "", 0, time.Second)
+	t.Cleanup(func() { client.Close() })
+	return NewRedisAdapter(client, ttl, discard())
+}
+
+func mustNotLoad[V any](t *testing.T) func(context.Context) (V, error) {
+	return func(context.Context) (V, error) {
+		t.Error("loaded, want a cache hit")
+		var zero V
+		return zero, errors.New("unexpected load")
+	}
+}
+
+var testCar = domain.Car{
+	ID:    1,
+	Name:  "BMW M3",
+	Year:  2021,
+	Image: "m3.jpg",
+	Specs: domain.Specs{
+		Engine:       "3.0L I6",
+		HP:           473,
+		Gearbox:      "8-speed automatic",
+		Transmission: domain.TransmissionAutomatic,
+		Drivetrain:   domain.DrivetrainRWD,
+	},
+	Manufacturer: domain.Manufacturer{ID: 1, Name: "BMW", Country: "Germany", FoundingYear: 1916},
+	Category:     domain.Category{ID: 2, Name: "Sedan"},
+}
+
+func TestRedisGetOrLoadCar(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t, "")
+	a := newTestRedisAdapter(t, f.addr, 90*time.Second)
+
+	var loads int
+	car, err := a.GetOrLoad(ctx, 1, func(context.Context) (domain.Car, error) {
+		loads++
+		return testCar, nil
+	})
+	if err != nil || loads != 1 || !reflect.DeepEqual(car, testCar) {
+		t.Fatalf("miss = %+v, %v after %d loads", car, err, loads)
+	}
+
+	f.mu.Lock()
+	ttl := f.ttls["viewer:car:1"]
+	tagTTL := f.ttls["viewer:tag:manufacturer:1"]
+	tagged := f.sets["viewer:tag:category:2"]
+	f.mu.Unlock()
+
+	if ttl != "90" {
+		t.Errorf("SET EX = %q, want 90", ttl)
+	}
+	if tagTTL != "180" {
+		t.Errorf("tag EXPIRE = %q, want twice the ttl", tagTTL)
+	}
+	if !slices.Equal(tagged, []string{"viewer:car:1"}) {
+		t.Errorf("category tag holds %q, want the car key", tagged)
+	}
+
+	car, err = a.GetOrLoad(ctx, 1, mustNotLoad[domain.Car](t))
+	if err != nil || !reflect.DeepEqual(car, testCar) {
+		t.Errorf("hit = %+v, %v, want the car back from JSON", car, err)
+	}
+}
+
+func TestRedisGetOrLoadMetadata(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t, "")
+	a := newTestRedisAdapter(t, f.addr, 0)
+
+	want := domain.Metadata{
+		Manufacturers: []domain.Manufacturer{testCar.Manufacturer, {ID: 2, Name: "Audi"}},
+		Categories:    []domain.Category{testCar.Category},
+	}
+
+	md, err := a.GetOrLoadMetadata(ctx, func(context.Context) (domain.Metadata, error) { return want, nil })
+	if err != nil || !reflect.DeepEqual(md, want) {
+		t.Fatalf("miss = %+v, %v", md, err)
+	}
+
+	f.mu.Lock()
+	_, hasTTL := f.ttls["viewer:metadata"]
+	f.mu.Unlock()
+	if hasTTL {
+		t.Error("SET sent EX for a ttl of 0")
+	}
+
+	md, err = a.GetOrLoadMetadata(ctx, mustNotLoad[domain.Metadata](t))
+	if err != nil || !reflect.DeepEqual(md, want) {
+		t.Errorf("hit = %+v, %v, want the metadata back from JSON", md, err)
+	}
+}
+
+func TestRedisInvalidateByTag(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t, "")
+	a := newTestRedisAdapter(t, f.addr, time.Minute)
+
+	for _, car := range []domain.Car{
+		{ID: 1, Manufacturer: domain.Manufacturer{ID: 1}, Category: domain.Category{ID: 1}},
+		{ID: 2, Manufacturer: domain.Manufacturer{ID: 1}, Category: domain.Category{ID: 2}},
+		{ID: 3, Manufacturer: domain.Manufacturer{ID: 2}, Category: domain.Category{ID: 2}},
+	} {
+		_, _ = a.GetOrLoad(ctx, car.ID, loadCar(car))
+	}
+	_, _ = a.GetOrLoadMetadata(ctx, func(context.Context) (domain.Metadata, error) { return domain.Metadata{}, nil })
+
+	a.InvalidateManufacturer(ctx, 1)
+
+	want := []string{
+		"viewer:car:3",
+		"viewer:tag:category:1", // outlives its key, harmless
+		"viewer:tag:category:2",
+		"viewer:tag:manufacturer:2",
+	}
+	if got := f.keys(); !slices.Equal(got, want) {
+		t.Errorf("after InvalidateManufacturer(1)\n got %q\nwant %q", got, want)
+	}
+
+	a.InvalidateCategory(ctx, 2)
+
+	want = []string{"viewer:tag:category:1", "viewer:tag:manufacturer:2"}
+	if got := f.keys(); !slices.Equal(got, want) {
+		t.Errorf("after InvalidateCategory(2)\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestRedisBackoffSkipsDeadServer(t *testing.T) {
+	ctx := context.Background()
+
+	// Accepts and hangs up right away, like a server going down
+	var dials atomic.Int32
+	addr := listen(t, func(net.Conn) { dials.Add(1) })
+	a := newTestRedisAdapter(t, addr, time.Minute)
+
+	var loads int
+	load := func(context.Context) (domain.Car, error) {
+		loads++
+		return testCar, nil
+	}
+
+	for range 3 {
+		if car, err := a.GetOrLoad(ctx, 1, load); err != nil || car.ID != 1 {
+			t.Fatalf("GetOrLoad = %+v, %v, want the loaded car without redis", car, err)
+		}
+	}
+	a.InvalidateManufacturer(ctx, 1)
+	a.InvalidateCar(ctx, 1)
+
+	if loads != 3 {
+		t.Errorf("loads = %d, want every request loaded", loads)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("redis tried %d times, want once before the backoff", n)
+	}
+	if a.up() {
+		t.Error("adapter isn't backing off")
+	}
+
+	// Past the window redis is tried again
+	a.downUntil.Store(time.Now().UnixNano())
+	_, _ = a.GetOrLoad(ctx, 1, load)
+	if n := dials.Load(); n != 2 {
+		t.Errorf("redis tried %d times after the backoff, want 2", n)
+	}
+}
+
+func TestRedisErrorReplyDoesntBackOff(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t, "ERR wrong kind of value")
+	a := newTestRedisAdapter(t, f.addr, time.Minute)
+
+	for range 2 {
+		if car, err := a.GetOrLoad(ctx, 1, loadCar(testCar)); err != nil || car.ID != 1 {
+			t.Fatalf("GetOrLoad = %+v, %v, want the loaded car", car, err)
+		}
+	}
+
+	// GET, SET and both SADDs on every request, no EXPIRE after a refused SADD:
+	// the server is up, it just refused them
+	if n := f.commands.Load(); n != 2*4 {
+		t.Errorf("redis got %d commands, want %d", n, 2*4)
+	}
+	if !a.up() {
+		t.Error("an error reply started a backoff")
+	}
+}
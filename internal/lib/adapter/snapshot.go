@@ -0,0 +1,84 @@
+package adapter
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Snapshot files inside the configured cache snapshot directory
+const (
+	carsSnapshotFile     = "cars.gob"
+	metadataSnapshotFile = "metadata.gob"
+)
+
+// SaveSnapshot writes the cached cars and metadata to dir, so the next start is warm.
+func (a *CacheAdapter) SaveSnapshot(dir string) error {
+	const op = "repository.adapter.SaveSnapshot"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return e.Wrap("failed to create cache snapshot directory", err)
+	}
+
+	if err := a.cars.SaveFile(filepath.Join(dir, carsSnapshotFile)); err != nil {
+		return e.Wrap("failed to save cars cache", err)
+	}
+
+	if err := a.metadata.SaveFile(filepath.Join(dir, metadataSnapshotFile)); err != nil {
+		return e.Wrap("failed to save metadata cache", err)
+	}
+
+	log.Info("cache snapshot saved",
+		slog.String("dir", dir),
+		slog.Int("cars_count", a.cars.ItemCount()),
+	)
+
+	return nil
+}
+
+// LoadSnapshot warms the cache from a snapshot saved by SaveSnapshot.
+// A missing or broken snapshot only means a cold start, so errors are logged and not returned.
+func (a *CacheAdapter) LoadSnapshot(dir string) {
+	const op = "repository.adapter.LoadSnapshot"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	loaders := map[string]func(path string) (int, int, error){
+		carsSnapshotFile:     a.cars.LoadFile,
+		metadataSnapshotFile: a.metadata.LoadFile,
+	}
+
+	for file, load := range loaders {
+		path := filepath.Join(dir, file)
+
+		loaded, skipped, err := load(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Info("no cache snapshot, starting cold", slog.String("path", path))
+			continue
+		}
+		if err != nil {
+			log.Warn("failed to load cache snapshot",
+				slog.String("path", path),
+				slog.Int("loaded", loaded),
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		log.Info("cache snapshot loaded",
+			slog.String("path", path),
+			slog.Int("loaded", loaded),
+			slog.Int("skipped", skipped),
+		)
+	}
+}
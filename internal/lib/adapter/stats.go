@@ -0,0 +1,27 @@
+package adapter
+
+import (
+	"strings"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
+)
+
+// KeyPrefix groups cache stats by key prefix: "car:" for every car, "metadata" for metadata.
+func KeyPrefix(key string) string {
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		return key[:i+1]
+	}
+	return key
+}
+
+// Stats returns the cache counters of cars and metadata per key prefix.
+func (a *CacheAdapter) Stats() map[string]domain.CacheStats {
+	stats := make(map[string]domain.CacheStats)
+	for _, groups := range []map[string]cache.Stats{a.cars.Stats(), a.metadata.Stats()} {
+		for prefix, s := range groups {
+			stats[prefix] = domain.CacheStats(s)
+		}
+	}
+	return stats
+}
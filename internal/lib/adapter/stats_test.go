@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
+)
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func loadCar(car domain.Car) func(context.Context) (domain.Car, error) {
+	return func(context.Context) (domain.Car, error) { return car, nil }
+}
+
+func TestStatsPerPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	cars := cache.New(time.Hour, 0,
+		cache.WithStatsGroups[string, domain.Car](KeyPrefix),
+		cache.WithMaxEntries[string, domain.Car](2),
+		cache.WithTags(CarTags),
+	)
+	metadata := cache.New(10*time.Millisecond, 0, cache.WithStatsGroups[string, domain.Metadata](KeyPrefix))
+	a := NewAdapter(cars, metadata, discard())
+
+	bmw := domain.Car{ID: 1, Manufacturer: domain.Manufacturer{ID: 1}, Category: domain.Category{ID: 1}}
+	audi := domain.Car{ID: 2, Manufacturer: domain.Manufacturer{ID: 2}, Category: domain.Category{ID: 1}}
+	mini := domain.Car{ID: 3, Manufacturer: domain.Manufacturer{ID: 3}, Category: domain.Category{ID: 2}}
+
+	_, _ = a.GetOrLoad(ctx, 1, loadCar(bmw)) // miss
+	_, _ = a.GetOrLoad(ctx, 1, loadCar(bmw)) // hit
+	_, _ = a.GetOrLoad(ctx, 2, loadCar(audi))
+	_, _ = a.GetOrLoad(ctx, 3, loadCar(mini)) // over capacity, evicts car 1
+	a.InvalidateManufacturer(ctx, 2)          // drops car 2
+
+	_, _ = a.GetOrLoadMetadata(ctx, func(context.Context) (domain.Metadata, error) {
+		return domain.Metadata{}, nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	metadata.DeleteExpired()
+
+	stats := a.Stats()
+	if len(stats) != 2 {
+		t.Errorf("stats for %d prefixes, want car: and metadata only: %+v", len(stats), stats)
+	}
+
+	want := domain.CacheStats{Hits: 1, Misses: 3, Sets: 3, Evictions: 1, Invalidated: 1, Items: 1}
+	if got := stats["car:"]; got != want {
+		t.Errorf("car: stats\n got %+v\nwant %+v", got, want)
+	}
+
+	want = domain.CacheStats{Misses: 1, Sets: 1, Expirations: 1}
+	if got := stats["metadata"]; got != want {
+		t.Errorf("metadata stats\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+func manufacturerTag(id int) string {
+	return fmt.Sprintf("manufacturer:%d", id)
+}
+
+func categoryTag(id int) string {
+	return fmt.Sprintf("category:%d", id)
+}
+
+// CarTags tags a cached car with the manufacturer and category embedded in it,
+// so the car is dropped when either of them changes.
+func CarTags(key string, car domain.Car) []string {
+	return []string{
+		manufacturerTag(car.Manufacturer.ID),
+		categoryTag(car.Category.ID),
+	}
+}
+
+// InvalidateManufacturer drops every cached car of the manufacturer, and the metadata that lists it.
+func (a *CacheAdapter) InvalidateManufacturer(ctx context.Context, id int) {
+	const op = "repository.adapter.InvalidateManufacturer"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	n := a.cars.InvalidateTag(manufacturerTag(id))
+	a.metadata.Delete("metadata")
+
+	log.Debug("manufacturer invalidated in cache",
+		slog.Int("manufacturer_id", id),
+		slog.Int("cars_count", n),
+	)
+}
+
+// InvalidateCategory drops every cached car of the category, and the metadata that lists it.
+func (a *CacheAdapter) InvalidateCategory(ctx context.Context, id int) {
+	const op = "repository.adapter.InvalidateCategory"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	n := a.cars.InvalidateTag(categoryTag(id))
+	a.metadata.Delete("metadata")
+
+	log.Debug("category invalidated in cache",
+		slog.Int("category_id", id),
+		slog.Int("cars_count", n),
+	)
+}
+
+// InvalidateCar drops a cached car.
+func (a *CacheAdapter) InvalidateCar(ctx context.Context, id int) {
+	a.cars.Delete(fmt.Sprintf("car:%d", id))
+}
+
+// InvalidateMetadata drops the cached metadata.
+func (a *CacheAdapter) InvalidateMetadata(ctx context.Context) {
+	a.metadata.Delete("metadata")
+}
@@ -0,0 +1,65 @@
+package adapter
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
+)
+
+func TestCarTags(t *testing.T) {
+	car := domain.Car{ID: 7, Manufacturer: domain.Manufacturer{ID: 3}, Category: domain.Category{ID: 5}}
+
+	want := []string{"manufacturer:3", "category:5"}
+	if got := CarTags("car:7", car); !slices.Equal(got, want) {
+		t.Errorf("CarTags = %q, want %q", got, want)
+	}
+}
+
+func TestInvalidateByTag(t *testing.T) {
+	ctx := context.Background()
+
+	cars := cache.New(time.Hour, 0, cache.WithTags(CarTags))
+	metadata := cache.New[string, domain.Metadata](time.Hour, 0)
+	a := NewAdapter(cars, metadata, discard())
+
+	fill := func() {
+		for _, car := range []domain.Car{
+			{ID: 1, Manufacturer: domain.Manufacturer{ID: 1}, Category: domain.Category{ID: 1}},
+			{ID: 2, Manufacturer: domain.Manufacturer{ID: 1}, Category: domain.Category{ID: 2}},
+			{ID: 3, Manufacturer: domain.Manufacturer{ID: 2}, Category: domain.Category{ID: 2}},
+		} {
+			_, _ = a.GetOrLoad(ctx, car.ID, loadCar(car))
+		}
+		_, _ = a.GetOrLoadMetadata(ctx, func(context.Context) (domain.Metadata, error) {
+			return domain.Metadata{}, nil
+		})
+	}
+	cached := func() []string {
+		var keys []string
+		for _, k := range []string{"car:1", "car:2", "car:3"} {
+			if _, ok := cars.Get(k); ok {
+				keys = append(keys, k)
+			}
+		}
+		if _, ok := metadata.Get("metadata"); ok {
+			keys = append(keys, "metadata")
+		}
+		return keys
+	}
+
+	fill()
+	a.InvalidateManufacturer(ctx, 1)
+	if got := cached(); !slices.Equal(got, []string{"car:3"}) {
+		t.Errorf("after InvalidateManufacturer(1) cached %q, want [car:3]", got)
+	}
+
+	fill()
+	a.InvalidateCategory(ctx, 2)
+	if got := cached(); !slices.Equal(got, []string{"car:1"}) {
+		t.Errorf("after InvalidateCategory(2) cached %q, want [car:1]", got)
+	}
+}
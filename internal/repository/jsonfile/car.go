@@ -0,0 +1,32 @@
+package jsonfile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) Car(ctx context.Context, id int) (domain.Car, error) {
+	const op = "repository.jsonfile.Car"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return domain.Car{}, e.Wrap("failed to load data", err)
+	}
+
+	i, ok := data.carIdx[id]
+	if !ok {
+		log.Warn("car not found", slog.Int("car_id", id))
+		return domain.Car{}, fmt.Errorf("car %d: %w", id, domain.ErrNotFound)
+	}
+
+	return data.cars[i], nil
+}
@@ -0,0 +1,42 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) CarsByIDs(ctx context.Context, viewedIDs map[int]int) ([]domain.Car, error) {
+	const op = "repository.jsonfile.CarsByIDs"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return []domain.Car{}, e.Wrap("failed to load data", err)
+	}
+
+	foundCars := make([]domain.Car, 0, len(viewedIDs))
+
+	// Keep the dataset order, same as the webapi repository does
+	for i := range data.cars {
+		if _, ok := viewedIDs[data.cars[i].ID]; ok {
+			foundCars = append(foundCars, data.cars[i])
+
+			if len(foundCars) == len(viewedIDs) {
+				break
+			}
+		}
+	}
+
+	log.Info("cars loaded",
+		slog.Int("cars_count", len(foundCars)),
+	)
+
+	return foundCars, nil
+}
@@ -0,0 +1,29 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) Cars(ctx context.Context) ([]domain.Car, error) {
+	const op = "repository.jsonfile.Cars"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return []domain.Car{}, e.Wrap("failed to load data", err)
+	}
+
+	// Copy, so callers can't modify the loaded dataset
+	cars := make([]domain.Car, len(data.cars))
+	copy(cars, data.cars)
+
+	return cars, nil
+}
@@ -0,0 +1,37 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Dataset returns the full dataset, used by the background refresher.
+// The file is re-read only if it was modified.
+func (r *FileRepository) Dataset(ctx context.Context) (domain.Dataset, error) {
+	const op = "repository.jsonfile.Dataset"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return domain.Dataset{}, e.Wrap("failed to load data", err)
+	}
+
+	// Copy, so callers can't modify the loaded dataset
+	ds := domain.Dataset{
+		Cars:          make([]domain.Car, len(data.cars)),
+		Manufacturers: make([]domain.Manufacturer, len(data.manufacturers)),
+		Categories:    make([]domain.Category, len(data.categories)),
+	}
+	copy(ds.Cars, data.cars)
+	copy(ds.Manufacturers, data.manufacturers)
+	copy(ds.Categories, data.categories)
+
+	return ds, nil
+}
@@ -0,0 +1,32 @@
+package jsonfile
+
+import (
+	"log/slog"
+	"net/url"
+)
+
+func (r *FileRepository) imageURL(carImage string) string {
+	const op = "repository.jsonfile.imageURL"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	if carImage == "" {
+		log.Warn("empty car image field")
+		return ""
+	}
+
+	URL, err := url.JoinPath(r.mediaHost, carImage)
+	if err != nil {
+		// Fallback: return original if join fails,
+		log.Warn("failed to join path",
+			slog.String("mediaHost", r.mediaHost),
+			slog.String("carImage", carImage),
+			slog.Any("error", err),
+		)
+		return carImage
+	}
+
+	return URL
+}
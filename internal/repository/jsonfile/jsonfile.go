@@ -0,0 +1,103 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Offline replacement for the webapi repository.
+// Reads the same data.json the carapi (Node.js) serves, so the viewer can run without it.
+// Images are expected to be served by the viewer itself under mediaHost.
+
+const mediaHost = "/media"
+
+type FileRepository struct {
+	log       *slog.Logger
+	path      string
+	mediaHost string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	data    *dataset
+}
+
+// dataset is the decoded and mapped content of the data file.
+type dataset struct {
+	cars          []domain.Car
+	carIdx        map[int]int // car ID -> index in cars
+	manufacturers []domain.Manufacturer
+	categories    []domain.Category
+}
+
+func New(log *slog.Logger, path string) (*FileRepository, error) {
+	r := &FileRepository{
+		log:       log,
+		path:      path,
+		mediaHost: mediaHost,
+	}
+
+	// Load once on startup, so a broken file is reported before the server starts.
+	if _, err := r.load(); err != nil {
+		return nil, e.Wrap("failed to load data file", err)
+	}
+
+	return r, nil
+}
+
+// load returns the current dataset, re-reading the file only if it was modified since the last read.
+func (r *FileRepository) load() (*dataset, error) {
+	const op = "repository.jsonfile.load"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		log.Error("failed to stat data file", slog.String("path", r.path), slog.Any("error", err))
+		return nil, e.Wrap("failed to stat data file", fmt.Errorf("%w: %w", domain.ErrUpstreamUnavailable, err))
+	}
+
+	r.mu.RLock()
+	if r.data != nil && info.ModTime().Equal(r.modTime) {
+		data := r.data
+		r.mu.RUnlock()
+		return data, nil
+	}
+	r.mu.RUnlock()
+
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		log.Error("failed to read data file", slog.String("path", r.path), slog.Any("error", err))
+		return nil, e.Wrap("failed to read data file", fmt.Errorf("%w: %w", domain.ErrUpstreamUnavailable, err))
+	}
+
+	var dto fileDTO
+	if err := json.Unmarshal(raw, &dto); err != nil {
+		log.Error("failed to decode data file", slog.String("path", r.path), slog.Any("error", err))
+		return nil, e.Wrap("failed to decode data file", fmt.Errorf("%w: %w", domain.ErrInvalidData, err))
+	}
+
+	data := r.mapDataset(dto)
+
+	r.mu.Lock()
+	r.data = data
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+
+	log.Info("data file loaded",
+		slog.String("path", r.path),
+		slog.Int("cars_count", len(data.cars)),
+		slog.Int("manufacturers_count", len(data.manufacturers)),
+		slog.Int("categories_count", len(data.categories)),
+	)
+
+	return data, nil
+}
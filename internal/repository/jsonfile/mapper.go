@@ -0,0 +1,82 @@
+package jsonfile
+
+import (
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// mapDataset maps DTOs to domain objects exactly the way the webapi repository does,
+// so CarStore and handlers can't tell which repository is used.
+func (r *FileRepository) mapDataset(dto fileDTO) *dataset {
+	vendors := make(map[int]domain.Manufacturer, len(dto.Manufacturers))
+	manufacturers := make([]domain.Manufacturer, 0, len(dto.Manufacturers))
+
+	for i := range dto.Manufacturers {
+		vendor := domain.Manufacturer{
+			ID:           dto.Manufacturers[i].ID,
+			Name:         dto.Manufacturers[i].Name,
+			Country:      dto.Manufacturers[i].Country,
+			FoundingYear: dto.Manufacturers[i].FoundingYear,
+		}
+		vendors[vendor.ID] = vendor
+		manufacturers = append(manufacturers, vendor)
+	}
+
+	categoriesByID := make(map[int]domain.Category, len(dto.Categories))
+	categories := make([]domain.Category, 0, len(dto.Categories))
+
+	for i := range dto.Categories {
+		category := domain.Category{
+			ID:   dto.Categories[i].ID,
+			Name: dto.Categories[i].Name,
+		}
+		categoriesByID[category.ID] = category
+		categories = append(categories, category)
+	}
+
+	cars := make([]domain.Car, 0, len(dto.CarModels))
+	carIdx := make(map[int]int, len(dto.CarModels))
+
+	for i := range dto.CarModels {
+		m := &dto.CarModels[i]
+
+		vendor := vendors[m.ManufacturerId]
+		category := categoriesByID[m.CategoryId]
+
+		car := domain.Car{
+			ID:    m.ID,
+			Name:  m.Name,
+			Year:  m.Year,
+			Image: r.imageURL(m.Image),
+
+			Specs: domain.Specs{
+				Engine:       m.Specs.Engine,
+				HP:           m.Specs.HP,
+				Gearbox:      m.Specs.Gearbox,
+				Transmission: domain.NormalizeGearbox(m.Specs.Gearbox),
+				Drivetrain:   m.Specs.Drivetrain,
+			},
+
+			Manufacturer: domain.Manufacturer{
+				ID:           m.ManufacturerId,
+				Name:         vendor.Name,
+				Country:      vendor.Country,
+				FoundingYear: vendor.FoundingYear,
+			},
+
+			Category: domain.Category{
+				ID:   m.CategoryId,
+				Name: category.Name,
+			},
+		}
+
+		carIdx[car.ID] = len(cars)
+		cars = append(cars, car)
+	}
+
+	return &dataset{
+		cars:          cars,
+		carIdx:        carIdx,
+		manufacturers: manufacturers,
+		categories:    categories,
+	}
+}
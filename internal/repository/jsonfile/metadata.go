@@ -0,0 +1,63 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) Metadata(ctx context.Context) (domain.Metadata, error) {
+	const op = "repository.jsonfile.Metadata"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return domain.Metadata{}, e.Wrap("failed to load data", err)
+	}
+
+	// Sets to deduplicate strings for metadata
+	uniqueDrivetrains := make(map[string]bool)
+	uniqueTransmissions := make(map[string]bool)
+
+	for i := range data.cars {
+		uniqueDrivetrains[data.cars[i].Specs.Drivetrain] = true
+		uniqueTransmissions[data.cars[i].Specs.Transmission] = true
+	}
+
+	drivetrains := make([]string, 0, len(uniqueDrivetrains))
+	for d := range uniqueDrivetrains {
+		drivetrains = append(drivetrains, d)
+	}
+
+	transmissions := make([]string, 0, len(uniqueTransmissions))
+	for t := range uniqueTransmissions {
+		transmissions = append(transmissions, t)
+	}
+
+	// Copy, so cached metadata doesn't share memory with the loaded dataset
+	vendors := make([]domain.Manufacturer, len(data.manufacturers))
+	copy(vendors, data.manufacturers)
+
+	categories := make([]domain.Category, len(data.categories))
+	copy(categories, data.categories)
+
+	log.Info("metadata loaded",
+		slog.Int("manufacturers_count", len(vendors)),
+		slog.Int("categories_count", len(categories)),
+		slog.Int("drivetrains_count", len(drivetrains)),
+		slog.Int("transmissions_count", len(transmissions)),
+	)
+
+	return domain.Metadata{
+		Manufacturers: vendors,
+		Categories:    categories,
+		Drivetrains:   drivetrains,
+		Transmissions: transmissions,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) RandomCars(ctx context.Context, limit int) ([]domain.Car, error) {
+	const op = "repository.jsonfile.RandomCars"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return []domain.Car{}, e.Wrap("failed to load data", err)
+	}
+
+	if limit > len(data.cars) {
+		limit = len(data.cars)
+	}
+
+	// Pick random indexes instead of shuffling a copy of the whole dataset
+	randomCars := make([]domain.Car, 0, limit)
+	for _, i := range rand.Perm(len(data.cars))[:limit] {
+		randomCars = append(randomCars, data.cars[i])
+	}
+
+	return randomCars, nil
+}
@@ -0,0 +1,55 @@
+package jsonfile
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+func (r *FileRepository) RecommendedCars(ctx context.Context, topManID int, secondManID int, topCatID int, top1CarID int, top2CarID int) ([]domain.Car, error) {
+	const op = "repository.jsonfile.RecommendedCars"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := r.load()
+	if err != nil {
+		log.Error("failed to load data", slog.Any("error", err))
+		return []domain.Car{}, e.Wrap("failed to load data", err)
+	}
+
+	// Same slots as in webapi: car of top man + top cat, car of second man + top cat, car of another man + top cat
+	result := make([]domain.Car, 0, 3)
+	var slot1, slot2, slot3 *domain.Car
+
+	for i := range data.cars {
+		c := &data.cars[i]
+
+		if c.ID == top1CarID || c.ID == top2CarID || c.Category.ID != topCatID {
+			continue
+		}
+
+		if slot1 == nil && c.Manufacturer.ID == topManID {
+			slot1 = c
+		} else if slot2 == nil && c.Manufacturer.ID == secondManID {
+			slot2 = c
+		} else if slot3 == nil && c.Manufacturer.ID != topManID && c.Manufacturer.ID != secondManID {
+			slot3 = c
+		}
+
+		if slot1 != nil && slot2 != nil && slot3 != nil {
+			break
+		}
+	}
+
+	for _, slot := range []*domain.Car{slot1, slot2, slot3} {
+		if slot != nil {
+			result = append(result, *slot)
+		}
+	}
+
+	return result, nil
+}
@@ -7,7 +7,7 @@ import (
 	"log/slog"
 	"net/url"
 	"strconv"
-	"strings"
+	"sync"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
@@ -30,8 +30,37 @@ func (w *WebRepository) Car(ctx context.Context, id int) (domain.Car, error) {
 		return domain.Car{}, e.Wrap("invalid url", fmt.Errorf("url: %s, error: %w", URL, err))
 	}
 
-	carData, err := w.client.DoRequest(ctx, URL)
-	if err != nil {
+	// The model and the lookups are fetched concurrently, so a cold car page costs one round-trip.
+	// The first failure cancels the other request.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg         sync.WaitGroup
+		carData    []byte
+		lookups    *lookups
+		carErr     error
+		lookupsErr error
+	)
+
+	wg.Go(func() {
+		if carData, carErr = w.client.DoRequest(ctx, URL); carErr != nil {
+			carErr = fetchError(carErr)
+			cancel(carErr)
+		}
+	})
+
+	wg.Go(func() {
+		if lookups, lookupsErr = w.lookups(ctx); lookupsErr != nil {
+			cancel(lookupsErr)
+		}
+	})
+
+	wg.Wait()
+
+	if carErr != nil || lookupsErr != nil {
+		// Cause is the error that failed first, not the cancellation it caused
+		err := context.Cause(ctx)
 		log.Error("failed to fetch car", slog.Any("error", err))
 		return domain.Car{}, e.Wrap("failed to fetch car", err)
 	}
@@ -40,67 +69,22 @@ func (w *WebRepository) Car(ctx context.Context, id int) (domain.Car, error) {
 
 	if err := json.Unmarshal(carData, &carDTO); err != nil {
 		log.Error("failed to decode API response for cars", slog.Any("error", err))
-		return domain.Car{}, e.Wrap("failed to decode API response for cars", err)
+		return domain.Car{}, e.Wrap("failed to decode API response for cars", decodeError(err))
 	}
 
-	// Fetch manufacturer to fill in the missing car info
-	vendor, err := w.Manufacturer(ctx, carDTO.ManufacturerId)
-	if err != nil {
-		log.Error("failed to get manufacturer", slog.Any("error", err))
-		return domain.Car{}, e.Wrap("failed to get manufacturer", err)
+	// A dangling reference doesn't make the car itself missing, so it's shown without vendor info, like in the snapshot.
+	vendor, ok := lookups.vendors[carDTO.ManufacturerId]
+	if !ok {
+		log.Warn("unknown manufacturer of car", slog.Int("car_id", id), slog.Int("manufacturer_id", carDTO.ManufacturerId))
 	}
 
-	// Fetch category to fill in the missing car info
-	category, err := w.Category(ctx, carDTO.CategoryId)
-	if err != nil {
-		log.Error("failed to get category", slog.Any("error", err))
-		return domain.Car{}, e.Wrap("failed to get category", err)
+	category, ok := lookups.categories[carDTO.CategoryId]
+	if !ok {
+		log.Warn("unknown category of car", slog.Int("car_id", id), slog.Int("category_id", carDTO.CategoryId))
 	}
 
 	// Map DTO to Domain object
-	car := domain.Car{
-
-		ID:    carDTO.ID,
-		Name:  carDTO.Name,
-		Year:  carDTO.Year,
-		Image: w.imageURL(carDTO.Image),
-
-		// Map the nested Specs struct
-		Specs: domain.Specs{
-			Engine:       carDTO.Specs.Engine,
-			HP:           carDTO.Specs.HP,
-			Gearbox:      carDTO.Specs.Gearbox,
-			Transmission: NormalizeGearbox(carDTO.Specs.Gearbox),
-			Drivetrain:   carDTO.Specs.Drivetrain,
-		},
-
-		// Map the nested Vendor struct
-		Manufacturer: domain.Manufacturer{
-			ID:           carDTO.ManufacturerId,
-			Name:         vendor.Name,
-			Country:      vendor.Country,
-			FoundingYear: vendor.FoundingYear,
-		},
-
-		// Map the nested Category struct
-		Category: domain.Category{
-			ID:   carDTO.CategoryId,
-			Name: category.Name,
-		},
-	}
+	car := w.mapCar(&carDTO, vendor, category)
 
 	return car, nil
 }
-
-// Helper function to prepopulate transmission field
-func NormalizeGearbox(gearbox string) string {
-
-	// If it contains "manual", it's a manual
-	if strings.Contains(strings.ToLower(gearbox), "manual") {
-		return domain.TransmissionManual
-	}
-
-	// Everything else (Automatic, CVT, DSG, Dual Clutch, Single-Speed)
-	// counts as "Automatic" for a general filter.
-	return domain.TransmissionAutomatic
-}
@@ -2,13 +2,13 @@ package webapi
 
 import (
 	"context"
-	"encoding/json"
 	"log/slog"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
 )
 
+// Cars returns the full catalog, every car is enriched with manufacturer and category.
 func (w *WebRepository) Cars(ctx context.Context) ([]domain.Car, error) {
 	const op = "repository.webapi.Cars"
 
@@ -16,52 +16,15 @@ func (w *WebRepository) Cars(ctx context.Context) ([]domain.Car, error) {
 		slog.String("op", op),
 	)
 
-	data, err := w.client.DoRequest(ctx, endpointModels)
+	snap, err := w.snapshot(ctx)
 	if err != nil {
-		log.Error("failed to fetch cars",
-			slog.String("endpoint:", endpointModels),
-			slog.Any("error", err),
-		)
-		return []domain.Car{}, e.Wrap("failed to fetch cars", err)
+		log.Error("failed to build snapshot", slog.Any("error", err))
+		return []domain.Car{}, e.Wrap("failed to build snapshot", err)
 	}
 
-	var dtos []carDTO
-
-	if err := json.Unmarshal(data, &dtos); err != nil {
-		log.Error("failed to decode API response for cars", slog.Any("error", err))
-		return []domain.Car{}, e.Wrap("failed to decode API response for cars", err)
-	}
-
-	// Map DTO to Domain
-	cars := make([]domain.Car, 0, len(dtos))
-
-	for i := range dtos {
-		car := domain.Car{
-
-			ID:    dtos[i].ID,
-			Name:  dtos[i].Name,
-			Year:  dtos[i].Year,
-			Image: w.imageURL(dtos[i].Image),
-
-			// Map the Nested Specs Struct
-			Specs: domain.Specs{
-				Engine:       dtos[i].Specs.Engine,
-				HP:           dtos[i].Specs.HP,
-				Gearbox:      dtos[i].Specs.Gearbox,
-				Transmission: NormalizeGearbox(dtos[i].Specs.Gearbox),
-				Drivetrain:   dtos[i].Specs.Drivetrain,
-			},
-
-			// PARTIAL FILL: We only know and need the ID right now.
-			Manufacturer: domain.Manufacturer{
-				ID: dtos[i].ManufacturerId,
-			},
-			Category: domain.Category{
-				ID: dtos[i].CategoryId,
-			},
-		}
-		cars = append(cars, car)
-	}
+	// Copy, the snapshot can be shared with concurrent callers
+	cars := make([]domain.Car, len(snap.cars))
+	copy(cars, snap.cars)
 
 	return cars, nil
 }
@@ -19,14 +19,14 @@ func (w *WebRepository) Categories(ctx context.Context) ([]domain.Category, erro
 	data, err := w.client.DoRequest(ctx, endpointCategories)
 	if err != nil {
 		log.Error("failed to fetch categories", slog.Any("error", err))
-		return []domain.Category{}, e.Wrap("failed to fetch categories", err)
+		return []domain.Category{}, e.Wrap("failed to fetch categories", fetchError(err))
 	}
 
 	var dtos []categoryDTO
 
 	if err := json.Unmarshal(data, &dtos); err != nil {
 		log.Error("failed to decode API response for categories", slog.Any("error", err))
-		return []domain.Category{}, e.Wrap("failed to decode API response for categories", err)
+		return []domain.Category{}, e.Wrap("failed to decode API response for categories", decodeError(err))
 	}
 
 	categories := make([]domain.Category, 0, len(dtos))
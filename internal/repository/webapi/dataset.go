@@ -0,0 +1,36 @@
+package webapi
+
+import (
+	"context"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Dataset pulls the full enriched dataset, used by the background refresher.
+func (w *WebRepository) Dataset(ctx context.Context) (domain.Dataset, error) {
+	const op = "repository.webapi.Dataset"
+
+	log := w.log.With(
+		slog.String("op", op),
+	)
+
+	snap, err := w.snapshot(ctx)
+	if err != nil {
+		log.Error("failed to build snapshot", slog.Any("error", err))
+		return domain.Dataset{}, e.Wrap("failed to build snapshot", err)
+	}
+
+	// Copy, the snapshot can be shared with concurrent callers
+	ds := domain.Dataset{
+		Cars:          make([]domain.Car, len(snap.cars)),
+		Manufacturers: make([]domain.Manufacturer, len(snap.manufacturers)),
+		Categories:    make([]domain.Category, len(snap.categories)),
+	}
+	copy(ds.Cars, snap.cars)
+	copy(ds.Manufacturers, snap.manufacturers)
+	copy(ds.Categories, snap.categories)
+
+	return ds, nil
+}
@@ -0,0 +1,25 @@
+package webapi
+
+import (
+	"errors"
+	"fmt"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/httpclient"
+)
+
+// fetchError translates client errors into domain errors, so upper layers don't depend on the client.
+func fetchError(err error) error {
+	switch {
+	case errors.Is(err, httpclient.ErrNotFound):
+		return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+	case errors.Is(err, httpclient.ErrUnavailable):
+		return fmt.Errorf("%w: %w", domain.ErrUpstreamUnavailable, err)
+	}
+	return err
+}
+
+// decodeError marks a response that couldn't be decoded as invalid data.
+func decodeError(err error) error {
+	return fmt.Errorf("%w: %w", domain.ErrInvalidData, err)
+}
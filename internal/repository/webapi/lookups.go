@@ -0,0 +1,98 @@
+package webapi
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// lookups are manufacturers and categories by ID. They change rarely,
+// so they are cached and a warm car page costs a single upstream call.
+// A new manufacturer shows up once the TTL runs out or the next snapshot is built.
+type lookups struct {
+	vendors    map[int]domain.Manufacturer
+	categories map[int]domain.Category
+	loadedAt   time.Time
+}
+
+func newLookups(vendors []domain.Manufacturer, categories []domain.Category) *lookups {
+	l := &lookups{
+		vendors:    make(map[int]domain.Manufacturer, len(vendors)),
+		categories: make(map[int]domain.Category, len(categories)),
+		loadedAt:   time.Now(),
+	}
+
+	for i := range vendors {
+		l.vendors[vendors[i].ID] = vendors[i]
+	}
+
+	for i := range categories {
+		l.categories[categories[i].ID] = categories[i]
+	}
+
+	return l
+}
+
+func (l *lookups) fresh() bool {
+	return l != nil && time.Since(l.loadedAt) < lookupsTTL
+}
+
+// lookups returns cached manufacturers and categories, or fetches both lists concurrently.
+// Concurrent cold callers share one fetch.
+func (w *WebRepository) lookups(ctx context.Context) (*lookups, error) {
+	if l := w.lookupCache.Load(); l.fresh() {
+		return l, nil
+	}
+
+	l, err, _ := w.lookupFlight.Do(ctx, "lookups", w.fetchLookups)
+	return l, err
+}
+
+func (w *WebRepository) fetchLookups(ctx context.Context) (*lookups, error) {
+	const op = "repository.webapi.fetchLookups"
+
+	log := w.log.With(
+		slog.String("op", op),
+	)
+
+	// The first failure cancels the other request
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg            sync.WaitGroup
+		vendors       []domain.Manufacturer
+		categories    []domain.Category
+		vendorsErr    error
+		categoriesErr error
+	)
+
+	wg.Go(func() {
+		if vendors, vendorsErr = w.Manufacturers(ctx); vendorsErr != nil {
+			cancel(vendorsErr)
+		}
+	})
+
+	wg.Go(func() {
+		if categories, categoriesErr = w.Categories(ctx); categoriesErr != nil {
+			cancel(categoriesErr)
+		}
+	})
+
+	wg.Wait()
+
+	if vendorsErr != nil || categoriesErr != nil {
+		err := context.Cause(ctx)
+		log.Error("failed to fetch lookups", slog.Any("error", err))
+		return nil, e.Wrap("failed to fetch lookups", err)
+	}
+
+	l := newLookups(vendors, categories)
+	w.lookupCache.Store(l)
+
+	return l, nil
+}
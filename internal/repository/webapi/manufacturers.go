@@ -19,14 +19,14 @@ func (w *WebRepository) Manufacturers(ctx context.Context) ([]domain.Manufacture
 	data, err := w.client.DoRequest(ctx, endpointManufacturers)
 	if err != nil {
 		log.Error("failed to fetch manufacturers", slog.Any("error", err))
-		return []domain.Manufacturer{}, e.Wrap("failed to fetch manufacturers", err)
+		return []domain.Manufacturer{}, e.Wrap("failed to fetch manufacturers", fetchError(err))
 	}
 
 	var dtos []manufacturerDTO
 
 	if err := json.Unmarshal(data, &dtos); err != nil {
 		log.Error("failed to decode API response for manufacturers", slog.Any("error", err))
-		return []domain.Manufacturer{}, e.Wrap("failed to decode API response for manufacturers", err)
+		return []domain.Manufacturer{}, e.Wrap("failed to decode API response for manufacturers", decodeError(err))
 	}
 
 	vendors := make([]domain.Manufacturer, 0, len(dtos))
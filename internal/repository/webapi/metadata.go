@@ -15,23 +15,14 @@ func (w *WebRepository) Metadata(ctx context.Context) (domain.Metadata, error) {
 		slog.String("op", op),
 	)
 
-	vendors, err := w.Manufacturers(ctx)
+	// One snapshot gives everything: manufacturers, categories and cars
+	snap, err := w.snapshot(ctx)
 	if err != nil {
-		log.Error("failed to get manufacturers", slog.Any("error", err))
-		return domain.Metadata{}, e.Wrap("failed to get manufacturers", err)
+		log.Error("failed to build snapshot", slog.Any("error", err))
+		return domain.Metadata{}, e.Wrap("failed to build snapshot", err)
 	}
 
-	categories, err := w.Categories(ctx)
-	if err != nil {
-		log.Error("failed to get categories", slog.Any("error", err))
-		return domain.Metadata{}, e.Wrap("failed to get categories", err)
-	}
-
-	cars, err := w.Cars(ctx)
-	if err != nil {
-		log.Error("failed to get cars", slog.Any("error", err))
-		return domain.Metadata{}, e.Wrap("failed to get cars", err)
-	}
+	vendors, categories, cars := snap.manufacturers, snap.categories, snap.cars
 
 	// Sets to deduplicate strings for metadata
 	uniqueDrivetrains := make(map[string]bool)
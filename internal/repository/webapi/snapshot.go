@@ -0,0 +1,136 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// snapshot is the whole webapi dataset joined in memory.
+// Every car in it is fully enriched with manufacturer and category info.
+type snapshot struct {
+	cars          []domain.Car
+	manufacturers []domain.Manufacturer
+	categories    []domain.Category
+}
+
+// snapshot returns the enriched dataset. Concurrent callers share one build,
+// so N visitors opening the catalog at once cause one download and one decode.
+// The returned slices are shared, callers must not modify them.
+func (w *WebRepository) snapshot(ctx context.Context) (snapshot, error) {
+	snap, err, _ := w.flight.Do(ctx, "snapshot", w.buildSnapshot)
+	return snap, err
+}
+
+// buildSnapshot builds the enriched dataset with 3 upstream calls in total:
+// models, manufacturers and categories, instead of 2 extra calls per car.
+func (w *WebRepository) buildSnapshot(ctx context.Context) (snapshot, error) {
+	const op = "repository.webapi.buildSnapshot"
+
+	log := w.log.With(
+		slog.String("op", op),
+	)
+
+	data, err := w.client.DoRequest(ctx, endpointModels)
+	if err != nil {
+		log.Error("failed to fetch cars",
+			slog.String("endpoint:", endpointModels),
+			slog.Any("error", err),
+		)
+		return snapshot{}, e.Wrap("failed to fetch cars", fetchError(err))
+	}
+
+	var dtos []carDTO
+
+	if err := json.Unmarshal(data, &dtos); err != nil {
+		log.Error("failed to decode API response for cars", slog.Any("error", err))
+		return snapshot{}, e.Wrap("failed to decode API response for cars", decodeError(err))
+	}
+
+	vendors, err := w.Manufacturers(ctx)
+	if err != nil {
+		log.Error("failed to get manufacturers", slog.Any("error", err))
+		return snapshot{}, e.Wrap("failed to get manufacturers", err)
+	}
+
+	categories, err := w.Categories(ctx)
+	if err != nil {
+		log.Error("failed to get categories", slog.Any("error", err))
+		return snapshot{}, e.Wrap("failed to get categories", err)
+	}
+
+	// Lookup tables for the join. They are fresh, so Car can reuse them too.
+	lookups := newLookups(vendors, categories)
+	w.lookupCache.Store(lookups)
+
+	// The Enrichment Loop
+	cars := make([]domain.Car, 0, len(dtos))
+
+	for i := range dtos {
+		vendor, ok := lookups.vendors[dtos[i].ManufacturerId]
+		if !ok {
+			log.Warn("unknown manufacturer of car",
+				slog.Int("car_id", dtos[i].ID),
+				slog.Int("manufacturer_id", dtos[i].ManufacturerId),
+			)
+		}
+
+		category, ok := lookups.categories[dtos[i].CategoryId]
+		if !ok {
+			log.Warn("unknown category of car",
+				slog.Int("car_id", dtos[i].ID),
+				slog.Int("category_id", dtos[i].CategoryId),
+			)
+		}
+
+		cars = append(cars, w.mapCar(&dtos[i], vendor, category))
+	}
+
+	log.Debug("snapshot built",
+		slog.Int("cars_count", len(cars)),
+		slog.Int("manufacturers_count", len(vendors)),
+		slog.Int("categories_count", len(categories)),
+	)
+
+	return snapshot{
+		cars:          cars,
+		manufacturers: vendors,
+		categories:    categories,
+	}, nil
+}
+
+// mapCar maps a car DTO and its already fetched manufacturer and category to the domain object.
+func (w *WebRepository) mapCar(dto *carDTO, vendor domain.Manufacturer, category domain.Category) domain.Car {
+	return domain.Car{
+		ID:    dto.ID,
+		Name:  dto.Name,
+		Year:  dto.Year,
+		Image: w.imageURL(dto.Image),
+
+		// Map the nested Specs struct
+		Specs: domain.Specs{
+			Engine:       dto.Specs.Engine,
+			HP:           dto.Specs.HP,
+			Gearbox:      dto.Specs.Gearbox,
+			Transmission: domain.NormalizeGearbox(dto.Specs.Gearbox),
+			Drivetrain:   dto.Specs.Drivetrain,
+		},
+
+		// Map the nested Vendor struct
+		Manufacturer: domain.Manufacturer{
+			ID:           dto.ManufacturerId,
+			Name:         vendor.Name,
+			Country:      vendor.Country,
+			FoundingYear: vendor.FoundingYear,
+		},
+
+		// Map the nested Category struct
+		Category: domain.Category{
+			ID:   dto.CategoryId,
+			Name: category.Name,
+		},
+	}
+}
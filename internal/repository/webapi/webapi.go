@@ -3,6 +3,10 @@ package webapi
 import (
 	"context"
 	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
 )
 
 /*
@@ -24,6 +28,7 @@ const (
 	endpointManufacturers = "manufacturers"
 	endpointCategories    = "categories"
 	mediaHost             = "http://localhost:3000/api/images"
+	lookupsTTL            = 10 * time.Minute
 )
 
 type Client interface {
@@ -34,6 +39,10 @@ type WebRepository struct {
 	log       *slog.Logger
 	client    Client
 	mediaHost string
+	flight    singleflight.Group[string, snapshot] // coalesces concurrent snapshot builds
+
+	lookupCache  atomic.Pointer[lookups] // manufacturers and categories for Car
+	lookupFlight singleflight.Group[string, *lookups]
 }
 
 func New(log *slog.Logger, client Client) *WebRepository {
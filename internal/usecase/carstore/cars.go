@@ -2,15 +2,20 @@ package carstore
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
+	"sync/atomic"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
 )
 
 // The Business Logic -> provide car/cars
 
-type CarProvider interface {
+// CarReader is implemented by both repositories and the in-memory Snapshot
+type CarReader interface {
 	Car(ctx context.Context, ID int) (domain.Car, error)
 	Cars(ctx context.Context) ([]domain.Car, error)
 	CarsByIDs(ctx context.Context, viewedIDs map[int]int) ([]domain.Car, error)
@@ -19,17 +24,37 @@ type CarProvider interface {
 	Metadata(ctx context.Context) (domain.Metadata, error)
 }
 
+type CarProvider interface {
+	CarReader
+	Dataset(ctx context.Context) (domain.Dataset, error) // full pull for the background refresher
+}
+
+// CacheProvider is a read-through cache: on a miss it calls load and keeps the result.
 type CacheProvider interface {
-	Get(ctx context.Context, id int) (domain.Car, bool)
-	Set(ctx context.Context, c domain.Car)
-	GetMetadata(ctx context.Context) (domain.Metadata, bool)
-	SetMetadata(ctx context.Context, m domain.Metadata)
+	GetOrLoad(ctx context.Context, id int, load func(ctx context.Context) (domain.Car, error)) (domain.Car, error)
+	GetOrLoadMetadata(ctx context.Context, load func(ctx context.Context) (domain.Metadata, error)) (domain.Metadata, error)
+
+	// Drop cached cars that embed a changed manufacturer or category
+	InvalidateManufacturer(ctx context.Context, id int)
+	InvalidateCategory(ctx context.Context, id int)
+
+	// Drop a changed car and the metadata after a refresh
+	InvalidateCar(ctx context.Context, id int)
+	InvalidateMetadata(ctx context.Context)
 }
 
 type CarStore struct {
-	log   *slog.Logger
-	repo  CarProvider
-	cache CacheProvider
+	log      *slog.Logger
+	repo     CarProvider
+	cache    CacheProvider
+	snapshot atomic.Pointer[Snapshot] // nil until the refresher loads the first dataset
+
+	suggestions   atomic.Pointer[suggestIndex] // search box autocomplete, nil until first built
+	suggestFlight singleflight.Group[string, *suggestIndex]
+
+	// Search index used without the refresher, the snapshot has its own
+	searchIdx    atomic.Pointer[searchIndex]
+	searchFlight singleflight.Group[string, *searchIndex]
 }
 
 func New(log *slog.Logger, r CarProvider, c CacheProvider) *CarStore {
@@ -47,23 +72,30 @@ func (s *CarStore) Car(ctx context.Context, ID int) (domain.Car, error) {
 		slog.String("op", op),
 	)
 
-	if car, found := s.cache.Get(ctx, ID); found {
-		log.Debug("loaded car from cache", "id", ID)
-		return car, nil
-	}
+	// The cache sits in front of the snapshot as well as the repo, the refresher drops what changed.
+	car, err := s.cache.GetOrLoad(ctx, ID, func(ctx context.Context) (domain.Car, error) {
+		// Once loaded, the snapshot has the whole catalog: a car it lacks doesn't exist
+		// (or will show up with the next refresh), so random IDs don't get to hammer upstream
+		car, err := s.source().Car(ctx, ID)
+		if err != nil {
+			return domain.Car{}, err
+		}
+
+		log.Info("car loaded",
+			slog.Int("car_id", ID),
+		)
 
-	car, err := s.repo.Car(ctx, ID)
+		return car, nil
+	})
 	if err != nil {
-		log.Error("failed to get car by id", slog.Any("error", err))
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Debug("car not found", slog.Int("car_id", ID))
+		} else {
+			log.Error("failed to get car by id", slog.Any("error", err))
+		}
 		return domain.Car{}, e.Wrap("failed to get car by id: %w", err)
 	}
 
-	s.cache.Set(ctx, car)
-
-	log.Info("car loaded",
-		slog.Int("car_id", ID),
-	)
-
 	return car, nil
 }
 
@@ -74,7 +106,7 @@ func (s *CarStore) Cars(ctx context.Context) ([]domain.Car, error) {
 		slog.String("op", op),
 	)
 
-	cars, err := s.repo.Cars(ctx)
+	cars, err := s.source().Cars(ctx)
 	if err != nil {
 		log.Error("failed to get cars catalog", slog.Any("error", err))
 		return nil, e.Wrap("failed to get cars catalog: %w", err)
@@ -97,7 +129,7 @@ func (s *CarStore) RandomCars(ctx context.Context) ([]domain.Car, error) {
 	const limit = 4
 
 	// Logic: Get 4 cars, maybe filter them, maybe handle errors specifically
-	cars, err := s.repo.RandomCars(ctx, limit)
+	cars, err := s.source().RandomCars(ctx, limit)
 	if err != nil {
 		log.Error("failed to get random cars", slog.Any("error", err))
 		return nil, e.Wrap("failed to get random cars: %w", err)
@@ -117,20 +149,26 @@ func (s *CarStore) Metadata(ctx context.Context) (domain.Metadata, error) {
 		slog.String("op", op),
 	)
 
-	if meta, found := s.cache.GetMetadata(ctx); found {
-		log.Debug("loaded metadata from cache")
-		return meta, nil
-	}
+	filters, err := s.cache.GetOrLoadMetadata(ctx, func(ctx context.Context) (domain.Metadata, error) {
+		filters, err := s.source().Metadata(ctx)
+		if err != nil {
+			return domain.Metadata{}, err
+		}
 
-	filters, err := s.repo.Metadata(ctx)
+		log.Info("metadata loaded")
+
+		return filters, nil
+	})
 	if err != nil {
 		log.Error("failed to get metadata", slog.Any("error", err))
 		return domain.Metadata{}, e.Wrap("failed to get metadata: %w", err)
 	}
 
-	s.cache.SetMetadata(ctx, filters)
-
-	log.Info("metadata loaded")
+	// Copies, the cached value is shared and callers may sort or append
+	filters.Manufacturers = slices.Clone(filters.Manufacturers)
+	filters.Categories = slices.Clone(filters.Categories)
+	filters.Drivetrains = slices.Clone(filters.Drivetrains)
+	filters.Transmissions = slices.Clone(filters.Transmissions)
 
 	return filters, nil
 }
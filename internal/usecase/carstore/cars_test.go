@@ -0,0 +1,151 @@
+package carstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/adapter"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
+)
+
+// fakeRepo serves a dataset and counts the single car lookups that reach it.
+// With carsErr set the car list fails, like an upstream outage.
+type fakeRepo struct {
+	ds       domain.Dataset
+	carCalls int
+	carsErr  error
+}
+
+func (r *fakeRepo) Car(ctx context.Context, ID int) (domain.Car, error) {
+	r.carCalls++
+	for _, c := range r.ds.Cars {
+		if c.ID == ID {
+			return c, nil
+		}
+	}
+	return domain.Car{}, domain.ErrNotFound
+}
+
+func (r *fakeRepo) Cars(ctx context.Context) ([]domain.Car, error) {
+	if r.carsErr != nil {
+		return nil, r.carsErr
+	}
+	return r.ds.Cars, nil
+}
+
+func (r *fakeRepo) CarsByIDs(ctx context.Context, viewedIDs map[int]int) ([]domain.Car, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) RandomCars(ctx context.Context, limit int) ([]domain.Car, error) { return nil, nil }
+
+func (r *fakeRepo) RecommendedCars(ctx context.Context, topManID, secondManID, topCatID, topCarID, secondCarID int) ([]domain.Car, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) Metadata(ctx context.Context) (domain.Metadata, error) {
+	return domain.Metadata{Manufacturers: r.ds.Manufacturers, Categories: r.ds.Categories}, nil
+}
+
+func (r *fakeRepo) Dataset(ctx context.Context) (domain.Dataset, error) { return r.ds, nil }
+
+func testDataset() domain.Dataset {
+	bmw := domain.Manufacturer{ID: 1, Name: "BMW"}
+	audi := domain.Manufacturer{ID: 2, Name: "Audi"}
+	sedan := domain.Category{ID: 1, Name: "Sedan"}
+
+	return domain.Dataset{
+		Cars: []domain.Car{
+			{ID: 1, Name: "BMW 3 Series", Manufacturer: bmw, Category: sedan},
+			{ID: 2, Name: "Audi A4", Manufacturer: audi, Category: sedan},
+		},
+		Manufacturers: []domain.Manufacturer{bmw, audi},
+		Categories:    []domain.Category{sedan},
+	}
+}
+
+func newTestStore(repo CarProvider) (*CarStore, *adapter.CacheAdapter) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cars := cache.New(time.Hour, 0,
+		cache.WithStatsGroups[string, domain.Car](adapter.KeyPrefix),
+		cache.WithTags(adapter.CarTags),
+	)
+	metadata := cache.New(time.Hour, 0, cache.WithStatsGroups[string, domain.Metadata](adapter.KeyPrefix))
+	ca := adapter.NewAdapter(cars, metadata, log)
+
+	return New(log, repo, ca), ca
+}
+
+func TestCarReadsThroughCacheAfterRefresh(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{ds: testDataset()}
+	store, ca := newTestStore(repo)
+
+	if err := store.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if car, err := store.Car(ctx, 1); err != nil || car.Name != "BMW 3 Series" {
+			t.Fatalf("Car(1) = %q, %v", car.Name, err)
+		}
+	}
+
+	st := ca.Stats()["car:"]
+	if st.Misses != 1 || st.Hits != 2 {
+		t.Errorf("car cache misses %d, hits %d, want 1 and 2", st.Misses, st.Hits)
+	}
+	if repo.carCalls != 0 {
+		t.Errorf("%d lookups reached the repo, the snapshot should answer them", repo.carCalls)
+	}
+
+	// Not in the snapshot: not found, without asking upstream
+	if _, err := store.Car(ctx, 99); !errors.Is(err, domain.ErrNotFound) || repo.carCalls != 0 {
+		t.Errorf("Car(99) err = %v, repo calls %d, want ErrNotFound and none", err, repo.carCalls)
+	}
+}
+
+func TestRefreshDropsChangedCars(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{ds: testDataset()}
+	store, _ := newTestStore(repo)
+
+	if err := store.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{1, 2} {
+		if _, err := store.Car(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Metadata(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Car 1 is renamed, Audi is renamed (car 2 embeds it), and a new make shows up
+	ds := testDataset()
+	ds.Cars[0].Name = "BMW 330i"
+	ds.Manufacturers[1].Name = "Audi AG"
+	ds.Cars[1].Manufacturer = ds.Manufacturers[1]
+	ds.Manufacturers = append(ds.Manufacturers, domain.Manufacturer{ID: 3, Name: "Mini"})
+	repo.ds = ds
+
+	if err := store.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if car, _ := store.Car(ctx, 1); car.Name != "BMW 330i" {
+		t.Errorf("car 1 is %q after refresh, want the new name", car.Name)
+	}
+	if car, _ := store.Car(ctx, 2); car.Manufacturer.Name != "Audi AG" {
+		t.Errorf("car 2 make is %q after refresh, want the new name", car.Manufacturer.Name)
+	}
+	if md, _ := store.Metadata(ctx); len(md.Manufacturers) != 3 {
+		t.Errorf("metadata lists %d makes after refresh, want 3", len(md.Manufacturers))
+	}
+}
@@ -2,8 +2,11 @@ package carstore
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
 	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
@@ -12,63 +15,243 @@ import (
 // Filtering for the catalog
 // get domain.FilterOptions, and return []domain.Car
 // filtering should happen here on the local slice of cars, based on the Filters.
-func (s *CarStore) Catalog(ctx context.Context, filters domain.FilterOptions) ([]domain.Car, error) {
+// Only the requested page is returned, out of range pages are clamped to the first or last one.
+func (s *CarStore) Catalog(ctx context.Context, filters domain.FilterOptions) (domain.CatalogPage, error) {
 	const op = "usecase.carstore.Catalog"
 
 	log := s.log.With(
 		slog.String("op", op),
 	)
 
-	allCars, err := s.repo.Cars(ctx)
+	src := s.source()
+
+	allCars, err := src.Cars(ctx)
 	if err != nil {
 		log.Error("failed to get cars catalog", slog.Any("error", err))
-		return nil, e.Wrap("failed to get cars catalog: %w", err)
+		return domain.CatalogPage{}, e.Wrap("failed to get cars catalog: %w", err)
+	}
+
+	// Search box query language, e.g. make:bmw hp>300 awd. A query with a syntax error is ignored.
+	var badQuery *domain.QueryError
+
+	pq, err := s.applyQuery(ctx, &filters)
+	if err != nil {
+		if !errors.As(err, &badQuery) {
+			log.Error("failed to apply search query", slog.Any("error", err))
+			return domain.CatalogPage{}, e.Wrap("failed to apply search query", err)
+		}
+		log.Debug("invalid search query", slog.Any("error", badQuery))
+		filters.SearchQuery = ""
+	}
+
+	// Full-text search, nil scores mean there is no text query.
+	// Without exact hits, typos are tolerated and a corrected query is suggested.
+	var (
+		scores     map[int]float64
+		suggestion string
+	)
+	if tokens := tokenize(filters.SearchQuery); len(tokens) > 0 {
+		idx, err := s.searchIndexFor(ctx, src, allCars)
+		if err != nil {
+			log.Error("failed to build search index", slog.Any("error", err))
+			return domain.CatalogPage{}, e.Wrap("failed to build search index", err)
+		}
+
+		// Quoted phrases match only as adjacent words
+		scores = idx.search(tokens, false)
+		idx.keepPhrases(scores, pq.phrases, false)
+		if len(scores) == 0 {
+			scores = idx.search(tokens, true)
+			idx.keepPhrases(scores, pq.phrases, true)
+			suggestion = idx.correct(tokens)
+		}
+
+		// Keep the fields and keywords of the query in the suggestion
+		if suggestion != "" && len(pq.structured) > 0 {
+			suggestion = strings.Join(pq.structured, " ") + " " + suggestion
+		}
 	}
 
 	// Apply "In-Memory" Filtering
-	finalList := s.filterCars(allCars, filters)
+	finalList, facets := s.filterCars(allCars, filters, scores)
 
-	return finalList, nil
+	// filterCars returns a new slice, so sorting doesn't touch the snapshot.
+	// Search results are ordered by relevance unless a sort was asked for.
+	if scores != nil && len(filters.Sort) == 0 {
+		sortByRelevance(finalList, scores)
+	} else {
+		sortCars(finalList, filters.Sort)
+	}
+
+	page := paginate(finalList, filters.Page, filters.PerPage)
+	page.Facets = facets
+	page.Suggestion = suggestion
+	page.QueryError = badQuery
+
+	return page, nil
 }
 
-func (s *CarStore) filterCars(allCars []domain.Car, f domain.FilterOptions) []domain.Car {
-	filtered := make([]domain.Car, 0, len(allCars))
+// applyQuery parses the search box query and merges it into the filters, the free text
+// stays as the SearchQuery. Syntax errors are returned as *domain.QueryError.
+func (s *CarStore) applyQuery(ctx context.Context, f *domain.FilterOptions) (parsedQuery, error) {
+	pq, qe := parseQuery(f.SearchQuery)
+	if qe != nil {
+		qe.Query = f.SearchQuery
+		return parsedQuery{}, qe
+	}
 
-	for _, car := range allCars {
-		// 1. Manufacturer
-		if f.ManufacturerID != 0 && car.Manufacturer.ID != f.ManufacturerID {
-			continue
+	// Names are looked up only when the query has them, it's free text most of the time
+	if len(pq.makes) > 0 || len(pq.categories) > 0 {
+		md, err := s.Metadata(ctx)
+		if err != nil {
+			return parsedQuery{}, e.Wrap("failed to get metadata for search query", err)
 		}
-		// 2. Category
-		if f.CategoryID != 0 && car.Category.ID != f.CategoryID {
-			continue
+		if qe := pq.resolve(md); qe != nil {
+			qe.Query = f.SearchQuery
+			return parsedQuery{}, qe
 		}
-		// 3. Year
-		if f.MinYear > 0 && car.Year < f.MinYear {
-			continue
-		}
-		// 4. HP
-		if f.MinHP > 0 && car.Specs.HP < f.MinHP {
+	}
+
+	pq.merge(f)
+
+	return pq, nil
+}
+
+// searchIndexFor returns the index built with the snapshot. When the cars come straight
+// from the repository, an index built from them is kept for searchIndexTTL,
+// and concurrent requests share one rebuild.
+func (s *CarStore) searchIndexFor(ctx context.Context, src CarReader, cars []domain.Car) (*searchIndex, error) {
+	if snap, ok := src.(*Snapshot); ok {
+		return snap.index, nil
+	}
+
+	if idx := s.searchIdx.Load(); idx != nil && time.Since(idx.builtAt) < searchIndexTTL {
+		return idx, nil
+	}
+
+	idx, err, _ := s.searchFlight.Do(ctx, "search", func(context.Context) (*searchIndex, error) {
+		idx := newSearchIndex(cars)
+		s.searchIdx.Store(idx)
+		return idx, nil
+	})
+	return idx, err
+}
+
+// Page sizes for the catalog, 12 fills three rows of the grid
+const (
+	DefaultPerPage = 12
+	MaxPerPage     = 96
+)
+
+func paginate(cars []domain.Car, page, perPage int) domain.CatalogPage {
+	if perPage <= 0 {
+		perPage = DefaultPerPage
+	}
+	perPage = min(perPage, MaxPerPage)
+
+	pages := max(1, (len(cars)+perPage-1)/perPage)
+	page = min(max(page, 1), pages)
+
+	from := (page - 1) * perPage
+	to := min(from+perPage, len(cars))
+
+	return domain.CatalogPage{
+		Cars:    cars[from:to],
+		Total:   len(cars),
+		Page:    page,
+		PerPage: perPage,
+		Pages:   pages,
+	}
+}
+
+// Multi-select fields of FilterOptions, each has its own facet counts
+const (
+	fieldManufacturer = iota
+	fieldCategory
+	fieldTransmission
+	fieldDrivetrain
+	numFields
+)
+
+// filterCars returns the cars matching every filter, and the facet counts in the same pass.
+// A car that fails only one multi-select field still counts for the options of that field.
+// scores are the full-text search results, nil when there is no text query.
+func (s *CarStore) filterCars(allCars []domain.Car, f domain.FilterOptions, scores map[int]float64) ([]domain.Car, domain.Facets) {
+	filtered := make([]domain.Car, 0, len(allCars))
+
+	facets := domain.Facets{
+		Manufacturers: make(map[int]int),
+		Categories:    make(map[int]int),
+		Transmissions: make(map[string]int),
+		Drivetrains:   make(map[string]int),
+	}
+
+	for _, car := range allCars {
+		// 1. Year
+		if !inRange(car.Year, f.MinYear, f.MaxYear) {
 			continue
 		}
-		// 5. Transmission
-		if f.Transmission != "" && car.Specs.Transmission != f.Transmission {
+		// 2. HP
+		if !inRange(car.Specs.HP, f.MinHP, f.MaxHP) {
 			continue
 		}
-		// 6. Drivetrain
-		if f.Drivetrain != "" && car.Specs.Drivetrain != f.Drivetrain {
-			continue
+		// 3. Text Search
+		if scores != nil {
+			if _, ok := scores[car.ID]; !ok {
+				continue
+			}
 		}
-		// 7. Text Search
-		if f.SearchQuery != "" {
-			matchName := strings.Contains(strings.ToLower(car.Name), strings.ToLower(f.SearchQuery))
 
-			if !matchName {
-				continue
+		// 4. Manufacturer, category, transmission and drivetrain
+		var missed [numFields]bool
+		missed[fieldManufacturer] = len(f.ManufacturerIDs) > 0 && !slices.Contains(f.ManufacturerIDs, car.Manufacturer.ID)
+		missed[fieldCategory] = len(f.CategoryIDs) > 0 && !slices.Contains(f.CategoryIDs, car.Category.ID)
+		missed[fieldTransmission] = len(f.Transmissions) > 0 && !slices.Contains(f.Transmissions, car.Specs.Transmission)
+		missed[fieldDrivetrain] = len(f.Drivetrains) > 0 && !slices.Contains(f.Drivetrains, car.Specs.Drivetrain)
+
+		misses := 0
+		for _, m := range missed {
+			if m {
+				misses++
 			}
 		}
 
-		filtered = append(filtered, car)
+		if misses == 0 {
+			filtered = append(filtered, car)
+		}
+
+		countFacets(&facets, &car, missed, misses)
+	}
+	return filtered, facets
+}
+
+// countFacets adds the car to the facets of every field whose other filters it passes
+func countFacets(facets *domain.Facets, car *domain.Car, missed [numFields]bool, misses int) {
+	counts := func(field int) bool {
+		return misses == 0 || (misses == 1 && missed[field])
+	}
+
+	if counts(fieldManufacturer) {
+		facets.Manufacturers[car.Manufacturer.ID]++
+	}
+	if counts(fieldCategory) {
+		facets.Categories[car.Category.ID]++
+	}
+	if counts(fieldTransmission) {
+		facets.Transmissions[car.Specs.Transmission]++
+	}
+	if counts(fieldDrivetrain) {
+		facets.Drivetrains[car.Specs.Drivetrain]++
+	}
+}
+
+// inRange checks v against inclusive bounds, a bound of 0 or less is ignored
+func inRange(v, lo, hi int) bool {
+	if lo > 0 && v < lo {
+		return false
+	}
+	if hi > 0 && v > hi {
+		return false
 	}
-	return filtered
+	return true
 }
@@ -0,0 +1,179 @@
+package carstore
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// A term matched with typos counts for less than an exact or prefix match
+const fuzzyPenalty = 0.3
+
+// maxEdits is how many typos a query token of n letters may have.
+// Short tokens get none, "bwm" is more likely another word than a typo.
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fuzzyTerms returns the car and manufacturer name terms closest to the token,
+// within maxEdits of it. Nothing when the token is too short to have typos.
+func (idx *searchIndex) fuzzyTerms(token string) ([]string, int) {
+	bound := maxEdits(utf8.RuneCountInString(token))
+	if bound == 0 {
+		return nil, 0
+	}
+
+	var closest []string
+	best := bound + 1
+
+	for term := range idx.names {
+		d := editDistance(token, term, bound)
+		if d > bound || d > best {
+			continue
+		}
+		if d < best {
+			best = d
+			closest = closest[:0]
+		}
+		closest = append(closest, term)
+	}
+
+	slices.Sort(closest)
+	return closest, best
+}
+
+// correct replaces the tokens that match nothing with the closest name terms, for "did you mean",
+// and resolves them to the name of the car or manufacturer they point at: "toyta corola" -> "Toyota Corolla".
+// Empty when there is nothing to correct.
+func (idx *searchIndex) correct(tokens []string) string {
+	terms := make([]string, 0, len(tokens))
+	changed := false
+
+	for _, t := range tokens {
+		if len(idx.matchingTerms(t)) > 0 {
+			terms = append(terms, t)
+			continue
+		}
+
+		closest, _ := idx.fuzzyTerms(t)
+		if len(closest) == 0 {
+			terms = append(terms, t)
+			continue
+		}
+
+		// Of equally close terms, suggest the one most cars have
+		best := slices.MaxFunc(closest, func(a, b string) int {
+			return len(idx.postings[a]) - len(idx.postings[b])
+		})
+		terms = append(terms, best)
+		changed = true
+	}
+
+	if !changed {
+		return ""
+	}
+
+	if name := idx.nameOf(terms); name != "" {
+		return name
+	}
+
+	words := make([]string, len(terms))
+	for i, t := range terms {
+		words[i] = idx.display(t)
+	}
+	return strings.Join(words, " ")
+}
+
+// nameOf returns the name of the best matching car, or of its manufacturer when every term is in that.
+// Empty when a term isn't a name word (a year, a body type) or no car has all of them.
+func (idx *searchIndex) nameOf(terms []string) string {
+	for _, t := range terms {
+		if _, ok := idx.names[t]; !ok {
+			return ""
+		}
+	}
+
+	scores := idx.search(terms, false)
+	if len(scores) == 0 {
+		return ""
+	}
+
+	// Best score, ties go to the lowest ID like in the results
+	ids := slices.Collect(maps.Keys(scores))
+	best := slices.MinFunc(ids, func(a, b int) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	title := idx.titles[best]
+	makeWords := tokenize(title.manufacturer)
+	for _, t := range terms {
+		if !slices.Contains(makeWords, t) {
+			return title.name
+		}
+	}
+	return title.manufacturer
+}
+
+// display returns a name term as it's written in the catalog, e.g. "corolla" -> "Corolla"
+func (idx *searchIndex) display(term string) string {
+	if d, ok := idx.names[term]; ok {
+		return d
+	}
+	return term
+}
+
+// editDistance is the Levenshtein distance between a and b, counted in runes.
+// It gives up once the distance is known to exceed bound and returns bound+1.
+func editDistance(a, b string, bound int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > bound {
+		return bound + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+
+		// Every later row is at least this far, no need to finish
+		if rowMin > bound {
+			return bound + 1
+		}
+		prev, cur = cur, prev
+	}
+
+	return min(prev[len(rb)], bound+1)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
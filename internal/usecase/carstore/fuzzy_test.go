@@ -0,0 +1,79 @@
+package carstore
+
+import (
+	"testing"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+func testSearchIndex() *searchIndex {
+	toyota := domain.Manufacturer{ID: 1, Name: "Toyota"}
+	mercedes := domain.Manufacturer{ID: 2, Name: "Mercedes-Benz"}
+	bmw := domain.Manufacturer{ID: 3, Name: "BMW"}
+	sedan := domain.Category{ID: 1, Name: "Sedan"}
+	suv := domain.Category{ID: 2, Name: "SUV"}
+
+	return newSearchIndex([]domain.Car{
+		{ID: 1, Name: "Toyota Corolla", Year: 2020, Manufacturer: toyota, Category: sedan},
+		{ID: 2, Name: "Toyota RAV4", Year: 2021, Manufacturer: toyota, Category: suv},
+		{ID: 3, Name: "Mercedes-Benz E-Class", Year: 2022, Manufacturer: mercedes, Category: sedan},
+		{ID: 4, Name: "BMW 3 Series", Year: 2020, Manufacturer: bmw, Category: sedan},
+		{ID: 5, Name: "BMW 5 Series", Year: 2021, Manufacturer: bmw, Category: sedan},
+	})
+}
+
+func TestMaxEdits(t *testing.T) {
+	for n, want := range map[int]int{0: 0, 1: 0, 3: 0, 4: 1, 6: 1, 7: 2, 12: 2} {
+		if got := maxEdits(n); got != want {
+			t.Errorf("maxEdits(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		bound int
+		want  int
+	}{
+		{"corola", "corolla", 1, 1},
+		{"mercedez", "mercedes", 2, 1},
+		{"toyta", "toyota", 1, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 2, 3}, // over the bound: bound+1
+		{"bmw", "audi", 1, 2},
+		{"škoda", "skoda", 1, 1}, // runes, not bytes
+		{"same", "same", 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.bound); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.bound, got, tt.want)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	idx := testSearchIndex()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"corola", "Toyota Corolla"},
+		{"toyta corola", "Toyota Corolla"},
+		{"toyta", "Toyota"},
+		{"mercedez", "Mercedes-Benz"},
+		{"bmw seies", "BMW 3 Series"},   // 3 and 5 Series score the same, the lower ID wins
+		{"corola 2020", "Corolla 2020"}, // a year isn't part of a name, only the typo is fixed
+		{"corolla", ""},                 // nothing to correct
+		{"bwm", ""},                     // too short for typos
+		{"xyzzyq", ""},                  // nothing close
+	}
+
+	for _, tt := range tests {
+		if got := idx.correct(tokenize(tt.query)); got != tt.want {
+			t.Errorf("correct(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package carstore
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// The search box understands a small query language on top of free text, e.g.
+//
+//	make:bmw,audi hp>300 year:2018..2022 awd "3 series"
+//
+// Fields are make, category, year, hp, transmission and drive (see queryFields for aliases).
+// Numbers take :N, :A..B, :A.., :..B, >N, >=N, <N and <=N. Several values of make, category,
+// transmission and drive are separated by commas and mean any of them.
+// Bare awd/fwd/rwd and manual/automatic set the drive and transmission,
+// everything else is free text for the full-text search.
+
+// Query fields, by every name they can be written with
+var queryFields = map[string]string{
+	"make":         "make",
+	"brand":        "make",
+	"manufacturer": "make",
+	"category":     "category",
+	"body":         "category",
+	"type":         "category",
+	"year":         "year",
+	"hp":           "hp",
+	"power":        "hp",
+	"transmission": "transmission",
+	"trans":        "transmission",
+	"drive":        "drive",
+	"drivetrain":   "drive",
+}
+
+var transmissionNames = map[string]string{
+	"manual":    domain.TransmissionManual,
+	"automatic": domain.TransmissionAutomatic,
+	"auto":      domain.TransmissionAutomatic,
+}
+
+var drivetrainNames = map[string]string{
+	"awd":   domain.DrivetrainAWD,
+	"4wd":   domain.DrivetrainAWD,
+	"all":   domain.DrivetrainAWD,
+	"fwd":   domain.DrivetrainFWD,
+	"front": domain.DrivetrainFWD,
+	"rwd":   domain.DrivetrainRWD,
+	"rear":  domain.DrivetrainRWD,
+}
+
+// No year or power is that large, and n+1 for ">n" can't overflow
+const maxQueryNumber = 100_000
+
+// parsedQuery is a search box query split into filters and free text
+type parsedQuery struct {
+	filters    domain.FilterOptions // ranges, transmissions and drivetrains
+	makes      []nameRef            // names, resolved to IDs with the metadata
+	categories []nameRef
+	text       []string   // free-text words and quoted phrases
+	phrases    [][]string // tokens of every quoted phrase of two or more words, they must be adjacent
+	structured []string   // the field and keyword parts as written, to rebuild the query
+}
+
+// nameRef is a manufacturer or category name with its position, for errors
+type nameRef struct {
+	name string
+	pos  int
+}
+
+// queryToken is a whitespace separated part of the query, quotes included
+type queryToken struct {
+	raw []rune
+	pos int // in runes from the start of the query
+}
+
+// parseQuery parses the search box query
+func parseQuery(query string) (parsedQuery, *domain.QueryError) {
+	var pq parsedQuery
+
+	tokens, err := splitQuery([]rune(query))
+	if err != nil {
+		return parsedQuery{}, err
+	}
+
+	for _, t := range tokens {
+		if err := pq.parseToken(t); err != nil {
+			return parsedQuery{}, err
+		}
+	}
+
+	return pq, nil
+}
+
+// splitQuery splits the query on whitespace outside of double quotes
+func splitQuery(q []rune) ([]queryToken, *domain.QueryError) {
+	var tokens []queryToken
+
+	for i := 0; i < len(q); {
+		if unicode.IsSpace(q[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		quoteAt := -1
+		for i < len(q) && (quoteAt >= 0 || !unicode.IsSpace(q[i])) {
+			if q[i] == '"' {
+				if quoteAt < 0 {
+					quoteAt = i
+				} else {
+					quoteAt = -1
+				}
+			}
+			i++
+		}
+
+		if quoteAt >= 0 {
+			return nil, queryErr(quoteAt, "unterminated quote")
+		}
+		tokens = append(tokens, queryToken{raw: q[start:i], pos: start})
+	}
+
+	return tokens, nil
+}
+
+func (pq *parsedQuery) parseToken(t queryToken) *domain.QueryError {
+	opAt := slices.IndexFunc(t.raw, func(r rune) bool {
+		return r == ':' || r == '>' || r == '<' || r == '=' || r == '"'
+	})
+
+	// Free text or a keyword, quoted text is always free text
+	if opAt < 0 || t.raw[opAt] == '"' {
+		word := unquote(t.raw)
+		if word == "" {
+			return nil
+		}
+		if opAt < 0 && pq.keyword(strings.ToLower(word)) {
+			pq.structured = append(pq.structured, string(t.raw))
+			return nil
+		}
+		if words := tokenize(word); opAt >= 0 && len(words) > 1 {
+			pq.phrases = append(pq.phrases, words)
+		}
+		pq.text = append(pq.text, word)
+		return nil
+	}
+
+	if opAt == 0 {
+		return queryErr(t.pos, "missing field name before %q", string(t.raw[0]))
+	}
+
+	key := strings.ToLower(string(t.raw[:opAt]))
+	field, ok := queryFields[key]
+	if !ok {
+		return queryErr(t.pos, "unknown field %q, use make, category, year, hp, transmission or drive", key)
+	}
+
+	op := string(t.raw[opAt])
+	if opAt+1 < len(t.raw) && t.raw[opAt+1] == '=' && (op == ">" || op == "<") {
+		op += "="
+	}
+
+	valueAt := opAt + len([]rune(op))
+	value := t.raw[valueAt:]
+	if unquote(value) == "" {
+		return queryErr(t.pos+valueAt, "missing value for %s", field)
+	}
+
+	pq.structured = append(pq.structured, string(t.raw))
+
+	switch field {
+	case "year", "hp":
+		lo, hi, err := parseRange(op, value, t.pos+valueAt)
+		if err != nil {
+			return err
+		}
+		if field == "year" {
+			tighten(&pq.filters.MinYear, &pq.filters.MaxYear, lo, hi)
+		} else {
+			tighten(&pq.filters.MinHP, &pq.filters.MaxHP, lo, hi)
+		}
+		return nil
+	}
+
+	// The rest are lists of names
+	if op != ":" && op != "=" {
+		return queryErr(t.pos+opAt, "%s can't be compared with %s, use %s:value", field, op, key)
+	}
+
+	for _, v := range splitValues(value, t.pos+valueAt) {
+		name := strings.ToLower(v.name)
+		if name == "" {
+			return queryErr(v.pos, "empty value in the list of %s", field)
+		}
+
+		switch field {
+		case "make":
+			pq.makes = append(pq.makes, v)
+		case "category":
+			pq.categories = append(pq.categories, v)
+		case "transmission":
+			tr, ok := transmissionNames[name]
+			if !ok {
+				return queryErr(v.pos, "unknown transmission %q, use manual or automatic", v.name)
+			}
+			pq.filters.Transmissions = appendUnique(pq.filters.Transmissions, tr)
+		case "drive":
+			dt, ok := drivetrainNames[name]
+			if !ok {
+				return queryErr(v.pos, "unknown drive %q, use awd, fwd or rwd", v.name)
+			}
+			pq.filters.Drivetrains = appendUnique(pq.filters.Drivetrains, dt)
+		}
+	}
+
+	return nil
+}
+
+// keyword handles the bare words that set a filter, like "awd" or "manual"
+func (pq *parsedQuery) keyword(word string) bool {
+	switch word {
+	case "awd", "4wd", "fwd", "rwd":
+		pq.filters.Drivetrains = appendUnique(pq.filters.Drivetrains, drivetrainNames[word])
+	case "manual", "automatic":
+		pq.filters.Transmissions = appendUnique(pq.filters.Transmissions, transmissionNames[word])
+	default:
+		return false
+	}
+	return true
+}
+
+// parseRange turns a number comparison into inclusive bounds, 0 means no bound
+func parseRange(op string, value []rune, pos int) (lo, hi int, err *domain.QueryError) {
+	num := func(s string, at int) (int, *domain.QueryError) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, queryErr(at, "%q is not a positive whole number", s)
+		}
+		if n > maxQueryNumber {
+			return 0, queryErr(at, "%d is too large", n)
+		}
+		return n, nil
+	}
+
+	v := unquote(value)
+
+	switch op {
+	case ">", ">=", "<", "<=":
+		n, err := num(v, pos)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch op {
+		case ">":
+			return n + 1, 0, nil
+		case ">=":
+			return n, 0, nil
+		case "<":
+			if n == 1 {
+				return 0, 0, queryErr(pos, "nothing is below 1")
+			}
+			return 0, n - 1, nil
+		default:
+			return 0, n, nil
+		}
+	}
+
+	from, to, isRange := strings.Cut(v, "..")
+	if !isRange {
+		n, err := num(v, pos)
+		return n, n, err
+	}
+
+	if from == "" && to == "" {
+		return 0, 0, queryErr(pos, "range needs at least one bound, like 2018..2022")
+	}
+	if from != "" {
+		if lo, err = num(from, pos); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to != "" {
+		if hi, err = num(to, pos+len([]rune(from))+2); err != nil {
+			return 0, 0, err
+		}
+	}
+	if lo > 0 && hi > 0 && lo > hi {
+		return 0, 0, queryErr(pos, "empty range %d..%d", lo, hi)
+	}
+
+	return lo, hi, nil
+}
+
+// tighten narrows the low/high pair by another pair of bounds, 0 means no bound
+func tighten(low, high *int, lo, hi int) {
+	if lo > *low {
+		*low = lo
+	}
+	if hi > 0 && (*high == 0 || hi < *high) {
+		*high = hi
+	}
+}
+
+// splitValues splits a comma separated list, keeping the position of each value
+func splitValues(value []rune, pos int) []nameRef {
+	var refs []nameRef
+	start := 0
+	for i := 0; i <= len(value); i++ {
+		if i == len(value) || value[i] == ',' {
+			refs = append(refs, nameRef{name: strings.TrimSpace(unquote(value[start:i])), pos: pos + start})
+			start = i + 1
+		}
+	}
+	return refs
+}
+
+// resolve looks up the make and category names in the metadata and adds their IDs to the filters.
+// A name matches exactly, ignoring case, or as the start of names ("mercedes" -> Mercedes-Benz).
+func (pq *parsedQuery) resolve(md domain.Metadata) *domain.QueryError {
+	for _, ref := range pq.makes {
+		ids := matchNames(ref.name, md.Manufacturers, func(m domain.Manufacturer) (int, string) { return m.ID, m.Name })
+		if len(ids) == 0 {
+			return queryErr(ref.pos, "unknown make %q", ref.name)
+		}
+		for _, id := range ids {
+			pq.filters.ManufacturerIDs = appendUnique(pq.filters.ManufacturerIDs, id)
+		}
+	}
+
+	for _, ref := range pq.categories {
+		ids := matchNames(ref.name, md.Categories, func(c domain.Category) (int, string) { return c.ID, c.Name })
+		if len(ids) == 0 {
+			return queryErr(ref.pos, "unknown category %q", ref.name)
+		}
+		for _, id := range ids {
+			pq.filters.CategoryIDs = appendUnique(pq.filters.CategoryIDs, id)
+		}
+	}
+
+	return nil
+}
+
+func matchNames[T any](name string, items []T, idName func(T) (int, string)) []int {
+	name = strings.ToLower(name)
+
+	var exact, prefix []int
+	for _, it := range items {
+		id, n := idName(it)
+		n = strings.ToLower(n)
+		switch {
+		case n == name:
+			exact = append(exact, id)
+		case strings.HasPrefix(n, name):
+			prefix = append(prefix, id)
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+	return prefix
+}
+
+// merge adds the query filters to the sidebar ones. Within a field the values add up,
+// ranges are narrowed, and the free text replaces the query.
+func (pq *parsedQuery) merge(f *domain.FilterOptions) {
+	for _, id := range pq.filters.ManufacturerIDs {
+		f.ManufacturerIDs = appendUnique(f.ManufacturerIDs, id)
+	}
+	for _, id := range pq.filters.CategoryIDs {
+		f.CategoryIDs = appendUnique(f.CategoryIDs, id)
+	}
+	for _, t := range pq.filters.Transmissions {
+		f.Transmissions = appendUnique(f.Transmissions, t)
+	}
+	for _, d := range pq.filters.Drivetrains {
+		f.Drivetrains = appendUnique(f.Drivetrains, d)
+	}
+
+	tighten(&f.MinYear, &f.MaxYear, pq.filters.MinYear, pq.filters.MaxYear)
+	tighten(&f.MinHP, &f.MaxHP, pq.filters.MinHP, pq.filters.MaxHP)
+
+	f.SearchQuery = strings.Join(pq.text, " ")
+}
+
+func appendUnique[T comparable](list []T, v T) []T {
+	if slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+func unquote(r []rune) string {
+	return strings.ReplaceAll(string(r), `"`, "")
+}
+
+func queryErr(pos int, format string, args ...any) *domain.QueryError {
+	return &domain.QueryError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,174 @@
+package carstore
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+var testMetadata = domain.Metadata{
+	Manufacturers: []domain.Manufacturer{
+		{ID: 1, Name: "BMW"},
+		{ID: 2, Name: "Audi"},
+		{ID: 3, Name: "Mercedes-Benz"},
+	},
+	Categories: []domain.Category{
+		{ID: 1, Name: "Sedan"},
+		{ID: 2, Name: "SUV"},
+	},
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    domain.FilterOptions
+		phrases [][]string
+	}{
+		{
+			query: "make:bmw hp>300 year:2018..2022 awd",
+			want: domain.FilterOptions{
+				ManufacturerIDs: []int{1},
+				Drivetrains:     []string{domain.DrivetrainAWD},
+				MinHP:           301,
+				MinYear:         2018,
+				MaxYear:         2022,
+			},
+		},
+		{
+			query: "brand:audi,mercedes body:suv manual",
+			want: domain.FilterOptions{
+				ManufacturerIDs: []int{2, 3},
+				CategoryIDs:     []int{2},
+				Transmissions:   []string{domain.TransmissionManual},
+			},
+		},
+		{
+			query: "hp>=200 hp<=400 year:..2010 trans:auto drive:rwd,fwd",
+			want: domain.FilterOptions{
+				Transmissions: []string{domain.TransmissionAutomatic},
+				Drivetrains:   []string{domain.DrivetrainRWD, domain.DrivetrainFWD},
+				MinHP:         200,
+				MaxHP:         400,
+				MaxYear:       2010,
+			},
+		},
+		{
+			query: `MAKE:"bmw" year:2020 coupe`,
+			want: domain.FilterOptions{
+				ManufacturerIDs: []int{1},
+				MinYear:         2020,
+				MaxYear:         2020,
+				SearchQuery:     "coupe",
+			},
+		},
+		{
+			query:   `bmw "3 series" "x5"`,
+			want:    domain.FilterOptions{SearchQuery: "bmw 3 series x5"},
+			phrases: [][]string{{"3", "series"}},
+		},
+		{
+			query: "  plain   text  ",
+			want:  domain.FilterOptions{SearchQuery: "plain text"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			pq, qe := parseQuery(tt.query)
+			if qe == nil {
+				qe = pq.resolve(testMetadata)
+			}
+			if qe != nil {
+				t.Fatalf("unexpected error at %d: %s", qe.Pos, qe.Msg)
+			}
+
+			var got domain.FilterOptions
+			pq.merge(&got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filters\n got %+v\nwant %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(pq.phrases, tt.phrases) {
+				t.Errorf("phrases = %q, want %q", pq.phrases, tt.phrases)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int // in runes
+	}{
+		{"mke:bmw", 0},
+		{":bmw", 0},
+		{"make:", 5},
+		{"make>bmw", 4},
+		{"make:bmw,,audi", 9},
+		{"bmw hp>abc", 7},
+		{"hp<1", 3},
+		{"hp:0", 3},
+		{"hp>999999999999999999999", 3},
+		{"year:2022..2018", 5},
+		{"year:..", 5},
+		{"year:2018..x", 11},
+		{`x "abc`, 2},
+		{"drive:xwd", 6},
+		{"trans:cvt", 6},
+		{"audi make:bmx", 10},
+		{"category:van", 9},
+		{"äöü make:x", 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			pq, qe := parseQuery(tt.query)
+			if qe == nil {
+				qe = pq.resolve(testMetadata)
+			}
+			if qe == nil {
+				t.Fatal("want an error")
+			}
+			if qe.Pos != tt.pos {
+				t.Errorf("error %q at %d, want at %d", qe.Msg, qe.Pos, tt.pos)
+			}
+		})
+	}
+}
+
+func FuzzParseQuery(f *testing.F) {
+	for _, q := range []string{
+		"make:bmw hp>300 year:2018..2022 awd",
+		`bmw "3 series"`,
+		"year:..2010 hp>=200",
+		`x "abc`,
+		"make:bmw,,audi",
+		"drive:awd,rwd trans:manual",
+		"äöü make:",
+	} {
+		f.Add(q)
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		pq, qe := parseQuery(query)
+		if qe == nil {
+			qe = pq.resolve(testMetadata)
+		}
+
+		if qe != nil {
+			if n := utf8.RuneCountInString(query); qe.Pos < 0 || qe.Pos > n {
+				t.Fatalf("error position %d outside of the %d runes of %q", qe.Pos, n, query)
+			}
+			return
+		}
+
+		var opts domain.FilterOptions
+		pq.merge(&opts)
+		// Contradicting bounds like year:2020 year:2010 are allowed, they just match nothing
+		if opts.MinYear < 0 || opts.MaxYear < 0 || opts.MinHP < 0 || opts.MaxHP < 0 {
+			t.Fatalf("negative bounds for %q: %+v", query, opts)
+		}
+	})
+}
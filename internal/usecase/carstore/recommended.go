@@ -22,11 +22,14 @@ func (s *CarStore) RecommendedCars(ctx context.Context, viewedIDs []int, exclude
 
 	const limit = 4
 
+	// Serve from the snapshot when it is loaded, the repo is only a fallback
+	src := s.source()
+
 	// 1. If no history return 4 random cars
 	if len(viewedIDs) == 0 {
 		log.Debug("empty viewedids history")
 
-		randomCars, err := s.repo.RandomCars(ctx, limit)
+		randomCars, err := src.RandomCars(ctx, limit)
 		if err != nil {
 			log.Error("failed to get random cars", slog.Any("error", err))
 			return nil, e.Wrap("failed to get random cars: %w", err)
@@ -73,11 +76,11 @@ func (s *CarStore) RecommendedCars(ctx context.Context, viewedIDs []int, exclude
 
 	// 2. Fetch cars data to analyze preferences
 	// TODO: maybe add cache here to check there first
-	foundCars, err := s.repo.CarsByIDs(ctx, uniqueIDsMap)
+	foundCars, err := src.CarsByIDs(ctx, uniqueIDsMap)
 	if err != nil {
 		log.Warn("failed to fetch cars by ids", slog.Any("error", err))
 
-		randomCars, err := s.repo.RandomCars(ctx, limit)
+		randomCars, err := src.RandomCars(ctx, limit)
 		if err != nil {
 			log.Error("failed to get random cars", slog.Any("error", err))
 			return nil, e.Wrap("failed to get random cars: %w", err)
@@ -143,11 +146,11 @@ func (s *CarStore) RecommendedCars(ctx context.Context, viewedIDs []int, exclude
 		recommendedCars = append(recommendedCars, *top2CarObj)
 	}
 
-	additionalVariants, err := s.repo.RecommendedCars(ctx, top1ManID, top2ManID, topCategoryID, top1CarID, top2CarID)
+	additionalVariants, err := src.RecommendedCars(ctx, top1ManID, top2ManID, topCategoryID, top1CarID, top2CarID)
 	if err != nil {
 		log.Warn("failed to fetch additional variants of cars", slog.Any("error", err))
 
-		randomCars, err := s.repo.RandomCars(ctx, limit)
+		randomCars, err := src.RandomCars(ctx, limit)
 		if err != nil {
 			log.Error("failed to get random cars", slog.Any("error", err))
 			return nil, e.Wrap("failed to get random cars: %w", err)
@@ -161,7 +164,7 @@ func (s *CarStore) RecommendedCars(ctx context.Context, viewedIDs []int, exclude
 	recommendedCars = append(recommendedCars, additionalVariants...)
 
 	// always 4 random cars
-	randomCars, err := s.repo.RandomCars(ctx, limit)
+	randomCars, err := src.RandomCars(ctx, limit)
 	if err != nil {
 		log.Error("failed to get random cars", slog.Any("error", err))
 		return recommendedCars, e.Wrap("failed to get random cars: %w", err)
@@ -0,0 +1,125 @@
+package carstore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Refresh pulls the full dataset from the repository, validates it and swaps the snapshot.
+// On any error the last good snapshot is kept.
+func (s *CarStore) Refresh(ctx context.Context) error {
+	const op = "usecase.carstore.Refresh"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	ds, err := s.repo.Dataset(ctx)
+	if err != nil {
+		log.Error("failed to pull dataset", slog.Any("error", err))
+		return e.Wrap("failed to pull dataset", err)
+	}
+
+	snap, warnings, err := newSnapshot(ds)
+	if err != nil {
+		log.Error("invalid dataset, keeping last snapshot", slog.Any("error", err))
+		return e.Wrap("invalid dataset", fmt.Errorf("%w: %w", domain.ErrInvalidData, err))
+	}
+
+	for _, w := range warnings {
+		log.Warn("dataset inconsistency", slog.String("warning", w))
+	}
+
+	old := s.snapshot.Swap(snap)
+	if old != nil {
+		s.invalidateChanged(ctx, old, snap)
+	}
+
+	s.suggestions.Store(newSuggestIndex(snap.cars, snap.metadata))
+
+	log.Info("snapshot refreshed",
+		slog.Int("cars_count", len(snap.cars)),
+		slog.Int("manufacturers_count", len(snap.metadata.Manufacturers)),
+		slog.Int("categories_count", len(snap.metadata.Categories)),
+	)
+
+	return nil
+}
+
+// invalidateChanged drops cached cars that changed or disappeared between two snapshots,
+// or whose manufacturer or category did, and the metadata, instead of serving them
+// stale until their TTL runs out.
+func (s *CarStore) invalidateChanged(ctx context.Context, old, cur *Snapshot) {
+	for i := range old.cars {
+		c := &old.cars[i]
+		if now, err := cur.Car(ctx, c.ID); err != nil || now != *c {
+			s.cache.InvalidateCar(ctx, c.ID)
+		}
+	}
+
+	// Cheap to reload from the snapshot, and new makes or drivetrains are changes too
+	s.cache.InvalidateMetadata(ctx)
+
+	vendors := make(map[int]domain.Manufacturer, len(cur.metadata.Manufacturers))
+	for _, m := range cur.metadata.Manufacturers {
+		vendors[m.ID] = m
+	}
+
+	for _, m := range old.metadata.Manufacturers {
+		if now, ok := vendors[m.ID]; !ok || now != m {
+			s.cache.InvalidateManufacturer(ctx, m.ID)
+		}
+	}
+
+	categories := make(map[int]domain.Category, len(cur.metadata.Categories))
+	for _, c := range cur.metadata.Categories {
+		categories[c.ID] = c
+	}
+
+	for _, c := range old.metadata.Categories {
+		if now, ok := categories[c.ID]; !ok || now != c {
+			s.cache.InvalidateCategory(ctx, c.ID)
+		}
+	}
+}
+
+// RunRefresher refreshes the snapshot right away and then every interval, until ctx is cancelled.
+// It blocks, so run it in a goroutine.
+func (s *CarStore) RunRefresher(ctx context.Context, interval time.Duration) {
+	const op = "usecase.carstore.RunRefresher"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("snapshot refresher started", slog.String("interval", interval.String()))
+
+	// Errors are already logged by Refresh, the last good snapshot stays in use
+	_ = s.Refresh(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Refresh(ctx)
+		case <-ctx.Done():
+			log.Info("snapshot refresher stopped")
+			return
+		}
+	}
+}
+
+// source returns the current snapshot if one was loaded, otherwise the repository itself.
+func (s *CarStore) source() CarReader {
+	if snap := s.snapshot.Load(); snap != nil {
+		return snap
+	}
+	return s.repo
+}
@@ -0,0 +1,261 @@
+package carstore
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// Without the refresher the search index is rebuilt from the repository this often
+const searchIndexTTL = 5 * time.Minute
+
+// How much a match in each field is worth, a hit in the model name beats one in the engine
+const (
+	weightName         = 3.0
+	weightManufacturer = 2.0
+	weightCategory     = 1.5
+	weightEngine       = 1.0
+	weightYear         = 1.0
+
+	// A query token that is only a prefix of an indexed term ("merc" -> "mercedes") counts for less
+	prefixPenalty = 0.5
+)
+
+// searchIndex is an inverted index over the catalog: term -> cars that contain it.
+// It is immutable once built, so it's shared by all requests without locks
+// and rebuilt together with the snapshot when the data changes.
+type searchIndex struct {
+	postings map[string][]posting
+	terms    []string           // sorted, for prefix lookups
+	names    map[string]string  // car and manufacturer name terms -> as written, for typo tolerance
+	fields   map[int][][]string // car ID -> tokens of each text field in order, for phrases
+	titles   map[int]carTitle   // car ID -> its names as written, for "did you mean"
+	docs     int
+	builtAt  time.Time
+}
+
+type carTitle struct {
+	name         string
+	manufacturer string
+}
+
+type posting struct {
+	carID  int
+	weight float64 // sum of the weights of the fields the term appears in
+}
+
+func newSearchIndex(cars []domain.Car) *searchIndex {
+	idx := &searchIndex{
+		postings: make(map[string][]posting),
+		names:    make(map[string]string),
+		fields:   make(map[int][][]string, len(cars)),
+		titles:   make(map[int]carTitle, len(cars)),
+		docs:     len(cars),
+		builtAt:  time.Now(),
+	}
+
+	for i := range cars {
+		c := &cars[i]
+
+		weights := make(map[string]float64)
+		addField := func(text string, weight float64) {
+			tokens := tokenize(text)
+			idx.fields[c.ID] = append(idx.fields[c.ID], tokens)
+
+			// Once per field, so "BMW BMW" isn't worth more than "BMW"
+			seen := make(map[string]bool)
+			for _, t := range tokens {
+				if !seen[t] {
+					seen[t] = true
+					weights[t] += weight
+				}
+			}
+		}
+
+		addField(c.Name, weightName)
+		addField(c.Manufacturer.Name, weightManufacturer)
+		addField(c.Category.Name, weightCategory)
+		addField(c.Specs.Engine, weightEngine)
+		addField(strconv.Itoa(c.Year), weightYear)
+
+		idx.titles[c.ID] = carTitle{name: c.Name, manufacturer: c.Manufacturer.Name}
+		for _, w := range slices.Concat(splitWords(c.Name), splitWords(c.Manufacturer.Name)) {
+			if t := strings.ToLower(w); idx.names[t] == "" {
+				idx.names[t] = w
+			}
+		}
+
+		for t, w := range weights {
+			idx.postings[t] = append(idx.postings[t], posting{carID: c.ID, weight: w})
+		}
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for t := range idx.postings {
+		idx.terms = append(idx.terms, t)
+	}
+	slices.Sort(idx.terms)
+
+	return idx
+}
+
+// search returns the relevance score of every car that matches all query tokens.
+// With fuzzy, a token that matches nothing as is may match car and manufacturer names with typos.
+// The result is never nil, so an empty map means nothing matched.
+func (idx *searchIndex) search(tokens []string, fuzzy bool) map[int]float64 {
+	var scores map[int]float64
+
+	for _, qt := range tokens {
+		// Best score of this token per car, over the exact and the prefix matches
+		best := make(map[int]float64)
+
+		terms := idx.matchingTerms(qt)
+
+		// Typos are tried only when the token matches nothing as is
+		edits := 0
+		if len(terms) == 0 && fuzzy {
+			terms, edits = idx.fuzzyTerms(qt)
+		}
+
+		for _, term := range terms {
+			penalty := 1.0
+			switch {
+			case edits > 0:
+				penalty = fuzzyPenalty / float64(edits)
+			case term != qt:
+				penalty = prefixPenalty
+			}
+
+			list := idx.postings[term]
+			idf := math.Log(1 + float64(idx.docs)/float64(len(list)))
+
+			for _, p := range list {
+				best[p.carID] = max(best[p.carID], p.weight*idf*penalty)
+			}
+		}
+
+		// AND across tokens: keep only the cars that matched every token so far
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			s, ok := best[id]
+			if !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] += s
+		}
+	}
+
+	if scores == nil {
+		scores = make(map[int]float64)
+	}
+	return scores
+}
+
+// keepPhrases drops the cars that don't have every phrase as adjacent words within one field.
+// Words of a phrase match like query tokens: exactly, as a prefix, or with typos when fuzzy.
+func (idx *searchIndex) keepPhrases(scores map[int]float64, phrases [][]string, fuzzy bool) {
+	for id := range scores {
+		for _, phrase := range phrases {
+			if !idx.hasPhrase(id, phrase, fuzzy) {
+				delete(scores, id)
+				break
+			}
+		}
+	}
+}
+
+func (idx *searchIndex) hasPhrase(carID int, phrase []string, fuzzy bool) bool {
+	for _, field := range idx.fields[carID] {
+		for start := 0; start+len(phrase) <= len(field); start++ {
+			matched := true
+			for i, w := range phrase {
+				if !wordMatches(w, field[start+i], fuzzy) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wordMatches reports whether a query word matches an indexed term, the same way search does
+func wordMatches(word, term string, fuzzy bool) bool {
+	if word == term {
+		return true
+	}
+
+	n := len([]rune(word))
+	if n >= 2 && strings.HasPrefix(term, word) {
+		return true
+	}
+
+	bound := maxEdits(n)
+	return fuzzy && bound > 0 && editDistance(word, term, bound) <= bound
+}
+
+// matchingTerms returns the indexed terms equal to or starting with the token.
+// Single characters match only exactly, a prefix that short would match half of the index.
+func (idx *searchIndex) matchingTerms(token string) []string {
+	if len([]rune(token)) < 2 {
+		if _, ok := idx.postings[token]; ok {
+			return []string{token}
+		}
+		return nil
+	}
+
+	i, _ := slices.BinarySearch(idx.terms, token)
+	j := i
+	for j < len(idx.terms) && strings.HasPrefix(idx.terms[j], token) {
+		j++
+	}
+	return idx.terms[i:j]
+}
+
+// tokenize lowercases the text and splits it into words and numbers
+func tokenize(text string) []string {
+	tokens := splitWords(text)
+	for i := range tokens {
+		tokens[i] = strings.ToLower(tokens[i])
+	}
+	return tokens
+}
+
+// splitWords splits the text into words and numbers, keeping the case.
+// Dots are kept inside words, so engine sizes like "2.0L" stay one word.
+func splitWords(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '.'
+	})
+
+	words := fields[:0]
+	for _, f := range fields {
+		if f = strings.Trim(f, "."); f != "" {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// sortByRelevance orders search results by score, best first, ties by ID
+func sortByRelevance(cars []domain.Car, scores map[int]float64) {
+	slices.SortFunc(cars, func(a, b domain.Car) int {
+		if c := cmp.Compare(scores[b.ID], scores[a.ID]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,181 @@
+package carstore
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// Snapshot is an immutable, validated copy of the whole dataset.
+// It is built by the refresher and swapped atomically, so readers never see a half updated catalog.
+// Snapshot implements CarReader, so CarStore can serve from it instead of the repo.
+type Snapshot struct {
+	cars     []domain.Car
+	carIdx   map[int]int // car ID -> index in cars
+	metadata domain.Metadata
+	index    *searchIndex // full-text search over cars
+}
+
+// newSnapshot validates the dataset and builds lookup tables and metadata.
+// Broken structure is an error, while references to unknown manufacturers or categories
+// are only returned as warnings: such cars are still displayable, just without vendor or body type info.
+func newSnapshot(ds domain.Dataset) (*Snapshot, []string, error) {
+	if len(ds.Cars) == 0 {
+		return nil, nil, fmt.Errorf("dataset has no cars")
+	}
+
+	vendors := make(map[int]bool, len(ds.Manufacturers))
+	for i := range ds.Manufacturers {
+		vendors[ds.Manufacturers[i].ID] = true
+	}
+
+	categories := make(map[int]bool, len(ds.Categories))
+	for i := range ds.Categories {
+		categories[ds.Categories[i].ID] = true
+	}
+
+	carIdx := make(map[int]int, len(ds.Cars))
+	var warnings []string
+
+	// Sets to deduplicate strings for metadata
+	uniqueDrivetrains := make(map[string]bool)
+	uniqueTransmissions := make(map[string]bool)
+
+	for i := range ds.Cars {
+		c := &ds.Cars[i]
+
+		if c.ID < 1 || c.Name == "" {
+			return nil, nil, fmt.Errorf("car at index %d has no id or name", i)
+		}
+		if _, dup := carIdx[c.ID]; dup {
+			return nil, nil, fmt.Errorf("duplicated car id %d", c.ID)
+		}
+		if !vendors[c.Manufacturer.ID] {
+			warnings = append(warnings, fmt.Sprintf("car %d refers to unknown manufacturer %d", c.ID, c.Manufacturer.ID))
+		}
+		if !categories[c.Category.ID] {
+			warnings = append(warnings, fmt.Sprintf("car %d refers to unknown category %d", c.ID, c.Category.ID))
+		}
+
+		carIdx[c.ID] = i
+		uniqueDrivetrains[c.Specs.Drivetrain] = true
+		uniqueTransmissions[c.Specs.Transmission] = true
+	}
+
+	drivetrains := make([]string, 0, len(uniqueDrivetrains))
+	for d := range uniqueDrivetrains {
+		drivetrains = append(drivetrains, d)
+	}
+
+	transmissions := make([]string, 0, len(uniqueTransmissions))
+	for t := range uniqueTransmissions {
+		transmissions = append(transmissions, t)
+	}
+
+	snap := &Snapshot{
+		cars:   ds.Cars,
+		carIdx: carIdx,
+		index:  newSearchIndex(ds.Cars),
+		metadata: domain.Metadata{
+			Manufacturers: ds.Manufacturers,
+			Categories:    ds.Categories,
+			Drivetrains:   drivetrains,
+			Transmissions: transmissions,
+		},
+	}
+
+	return snap, warnings, nil
+}
+
+func (s *Snapshot) Car(ctx context.Context, ID int) (domain.Car, error) {
+	i, ok := s.carIdx[ID]
+	if !ok {
+		return domain.Car{}, fmt.Errorf("car %d in snapshot: %w", ID, domain.ErrNotFound)
+	}
+
+	return s.cars[i], nil
+}
+
+func (s *Snapshot) Cars(ctx context.Context) ([]domain.Car, error) {
+	// Copy, the snapshot must stay immutable
+	cars := make([]domain.Car, len(s.cars))
+	copy(cars, s.cars)
+
+	return cars, nil
+}
+
+func (s *Snapshot) CarsByIDs(ctx context.Context, viewedIDs map[int]int) ([]domain.Car, error) {
+	foundCars := make([]domain.Car, 0, len(viewedIDs))
+
+	for i := range s.cars {
+		if _, ok := viewedIDs[s.cars[i].ID]; ok {
+			foundCars = append(foundCars, s.cars[i])
+
+			if len(foundCars) == len(viewedIDs) {
+				break
+			}
+		}
+	}
+
+	return foundCars, nil
+}
+
+func (s *Snapshot) RandomCars(ctx context.Context, limit int) ([]domain.Car, error) {
+	if limit > len(s.cars) {
+		limit = len(s.cars)
+	}
+
+	randomCars := make([]domain.Car, 0, limit)
+	for _, i := range rand.Perm(len(s.cars))[:limit] {
+		randomCars = append(randomCars, s.cars[i])
+	}
+
+	return randomCars, nil
+}
+
+func (s *Snapshot) RecommendedCars(ctx context.Context, topManID int, secondManID int, topCatID int, top1CarID int, top2CarID int) ([]domain.Car, error) {
+	// Same slots as the repositories: car of top man + top cat, car of second man + top cat, car of another man + top cat
+	result := make([]domain.Car, 0, 3)
+	var slot1, slot2, slot3 *domain.Car
+
+	for i := range s.cars {
+		c := &s.cars[i]
+
+		if c.ID == top1CarID || c.ID == top2CarID || c.Category.ID != topCatID {
+			continue
+		}
+
+		if slot1 == nil && c.Manufacturer.ID == topManID {
+			slot1 = c
+		} else if slot2 == nil && c.Manufacturer.ID == secondManID {
+			slot2 = c
+		} else if slot3 == nil && c.Manufacturer.ID != topManID && c.Manufacturer.ID != secondManID {
+			slot3 = c
+		}
+
+		if slot1 != nil && slot2 != nil && slot3 != nil {
+			break
+		}
+	}
+
+	for _, slot := range []*domain.Car{slot1, slot2, slot3} {
+		if slot != nil {
+			result = append(result, *slot)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Snapshot) Metadata(ctx context.Context) (domain.Metadata, error) {
+	// Copies, the snapshot must stay immutable even if the caller sorts or appends
+	return domain.Metadata{
+		Manufacturers: slices.Clone(s.metadata.Manufacturers),
+		Categories:    slices.Clone(s.metadata.Categories),
+		Drivetrains:   slices.Clone(s.metadata.Drivetrains),
+		Transmissions: slices.Clone(s.metadata.Transmissions),
+	}, nil
+}
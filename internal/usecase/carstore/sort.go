@@ -0,0 +1,55 @@
+package carstore
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+// sortCars orders cars by the keys, one after another. Cars equal on every key
+// are ordered by ID, so the order is stable between requests and pages.
+// Without keys the upstream order is kept.
+func sortCars(cars []domain.Car, keys []domain.SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	slices.SortFunc(cars, func(a, b domain.Car) int {
+		for _, k := range keys {
+			c := compareBy(k.Field, &a, &b)
+			if k.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+func compareBy(field string, a, b *domain.Car) int {
+	switch field {
+	case domain.SortHP:
+		return cmp.Compare(a.Specs.HP, b.Specs.HP)
+	case domain.SortYear:
+		return cmp.Compare(a.Year, b.Year)
+	case domain.SortName:
+		return compareText(a.Name, b.Name)
+	case domain.SortManufacturer:
+		return compareText(a.Manufacturer.Name, b.Manufacturer.Name)
+	case domain.SortCategory:
+		return compareText(a.Category.Name, b.Category.Name)
+	}
+	return 0
+}
+
+// compareText compares case-insensitively, so "bmw" isn't sorted after "Volvo"
+func compareText(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,188 @@
+package carstore
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+	"gitea.kood.tech/ivanandreev/viewer/internal/lib/e"
+)
+
+// Without the refresher the suggest index is rebuilt from the repository this often
+const suggestIndexTTL = 5 * time.Minute
+
+// suggestIndex is a prefix index over car, manufacturer and category names for autocomplete.
+// It is immutable once built. CarStore swaps the whole index atomically on rebuild,
+// so requests read it without locks while a new one is being built.
+type suggestIndex struct {
+	entries []suggestEntry
+	keys    []suggestKey // sorted by prefix
+	builtAt time.Time
+}
+
+type suggestEntry struct {
+	suggestion domain.Suggestion
+	cars       int // how many cars it leads to, popular makes and body types go first
+}
+
+// suggestKey makes an entry findable from the start of each of its words,
+// so "ser" finds "BMW 3 Series" as well as "Se" finds "Sedan"
+type suggestKey struct {
+	prefix string // normalized text from a word start to the end
+	entry  int
+	word   int // 0 when the key is the whole text
+}
+
+func newSuggestIndex(cars []domain.Car, md domain.Metadata) *suggestIndex {
+	idx := &suggestIndex{builtAt: time.Now()}
+
+	vendorCars := make(map[int]int)
+	categoryCars := make(map[int]int)
+	for i := range cars {
+		vendorCars[cars[i].Manufacturer.ID]++
+		categoryCars[cars[i].Category.ID]++
+	}
+
+	for _, m := range md.Manufacturers {
+		idx.add(domain.Suggestion{Type: domain.SuggestManufacturer, ID: m.ID, Text: m.Name}, vendorCars[m.ID])
+	}
+	for _, c := range md.Categories {
+		idx.add(domain.Suggestion{Type: domain.SuggestCategory, ID: c.ID, Text: c.Name}, categoryCars[c.ID])
+	}
+	for i := range cars {
+		idx.add(domain.Suggestion{Type: domain.SuggestCar, ID: cars[i].ID, Text: cars[i].Name}, 1)
+	}
+
+	slices.SortFunc(idx.keys, func(a, b suggestKey) int {
+		return strings.Compare(a.prefix, b.prefix)
+	})
+
+	return idx
+}
+
+func (idx *suggestIndex) add(s domain.Suggestion, cars int) {
+	words := tokenize(s.Text)
+	if len(words) == 0 {
+		return
+	}
+
+	entry := len(idx.entries)
+	idx.entries = append(idx.entries, suggestEntry{suggestion: s, cars: cars})
+
+	for i := range words {
+		idx.keys = append(idx.keys, suggestKey{
+			prefix: strings.Join(words[i:], " "),
+			entry:  entry,
+			word:   i,
+		})
+	}
+}
+
+// lookup returns up to limit entries that have a word starting with the query, best first:
+// matches at the start of the name, then makes and body types, then by popularity and length.
+func (idx *suggestIndex) lookup(query string, limit int) []domain.Suggestion {
+	q := strings.Join(tokenize(query), " ")
+	if q == "" || limit <= 0 {
+		return nil
+	}
+
+	// Each entry once, with the earliest word it matched at
+	matched := make(map[int]int)
+
+	i, _ := slices.BinarySearchFunc(idx.keys, q, func(k suggestKey, q string) int {
+		return strings.Compare(k.prefix, q)
+	})
+	for ; i < len(idx.keys) && strings.HasPrefix(idx.keys[i].prefix, q); i++ {
+		k := idx.keys[i]
+		if w, ok := matched[k.entry]; !ok || k.word < w {
+			matched[k.entry] = k.word
+		}
+	}
+
+	found := make([]int, 0, len(matched))
+	for entry := range matched {
+		found = append(found, entry)
+	}
+
+	slices.SortFunc(found, func(a, b int) int {
+		ea, eb := &idx.entries[a], &idx.entries[b]
+		return cmp.Or(
+			cmp.Compare(min(matched[a], 1), min(matched[b], 1)),
+			cmp.Compare(typeRank(ea.suggestion.Type), typeRank(eb.suggestion.Type)),
+			cmp.Compare(eb.cars, ea.cars),
+			cmp.Compare(len(ea.suggestion.Text), len(eb.suggestion.Text)),
+			strings.Compare(ea.suggestion.Text, eb.suggestion.Text),
+		)
+	})
+
+	suggestions := make([]domain.Suggestion, 0, min(limit, len(found)))
+	for _, entry := range found[:min(limit, len(found))] {
+		suggestions = append(suggestions, idx.entries[entry].suggestion)
+	}
+	return suggestions
+}
+
+// typeRank puts makes and body types, that lead to a filtered catalog, before single cars
+func typeRank(t string) int {
+	switch t {
+	case domain.SuggestManufacturer:
+		return 0
+	case domain.SuggestCategory:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Suggest returns autocomplete entries for the search box
+func (s *CarStore) Suggest(ctx context.Context, query string, limit int) ([]domain.Suggestion, error) {
+	const op = "usecase.carstore.Suggest"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	idx := s.suggestions.Load()
+
+	// The refresher keeps the index up to date, without it the index is built here and expires.
+	// Concurrent requests share one rebuild, and a failed one keeps serving the old index.
+	if idx == nil || (s.snapshot.Load() == nil && time.Since(idx.builtAt) > suggestIndexTTL) {
+		rebuilt, err, _ := s.suggestFlight.Do(ctx, "suggest", func(ctx context.Context) (*suggestIndex, error) {
+			cars, err := s.source().Cars(ctx)
+			if err != nil {
+				log.Error("failed to get cars for suggestions", slog.Any("error", err))
+				return nil, e.Wrap("failed to get cars for suggestions", err)
+			}
+
+			md, err := s.Metadata(ctx)
+			if err != nil {
+				log.Error("failed to get metadata for suggestions", slog.Any("error", err))
+				return nil, e.Wrap("failed to get metadata for suggestions", err)
+			}
+
+			idx := newSuggestIndex(cars, md)
+			s.suggestions.Store(idx)
+
+			log.Debug("suggest index built", slog.Int("keys", len(idx.keys)))
+
+			return idx, nil
+		})
+		switch {
+		case err == nil:
+			idx = rebuilt
+		case idx == nil:
+			return nil, err
+		default:
+			log.Warn("serving stale suggestions",
+				slog.Duration("age", time.Since(idx.builtAt)),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	return idx.lookup(query, limit), nil
+}
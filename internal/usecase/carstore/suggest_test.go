@@ -0,0 +1,115 @@
+package carstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/internal/domain"
+)
+
+func testSuggestIndex() *suggestIndex {
+	bmw := domain.Manufacturer{ID: 1, Name: "BMW"}
+	subaru := domain.Manufacturer{ID: 2, Name: "Subaru"}
+	mini := domain.Manufacturer{ID: 3, Name: "Mini"}
+	sedan := domain.Category{ID: 1, Name: "Sedan"}
+	suv := domain.Category{ID: 2, Name: "SUV"}
+	hatch := domain.Category{ID: 3, Name: "Hatchback"}
+
+	cars := []domain.Car{
+		{ID: 1, Name: "BMW 3 Series", Manufacturer: bmw, Category: sedan},
+		{ID: 2, Name: "Subaru Outback", Manufacturer: subaru, Category: suv},
+		{ID: 3, Name: "Subaru Impreza", Manufacturer: subaru, Category: sedan},
+		{ID: 4, Name: "Mini Cooper S", Manufacturer: mini, Category: hatch},
+	}
+
+	return newSuggestIndex(cars, domain.Metadata{
+		Manufacturers: []domain.Manufacturer{bmw, subaru, mini},
+		Categories:    []domain.Category{sedan, suv, hatch},
+	})
+}
+
+func texts(suggestions []domain.Suggestion) []string {
+	out := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		out[i] = s.Text
+	}
+	return out
+}
+
+func TestSuggestOrder(t *testing.T) {
+	idx := testSuggestIndex()
+
+	// Name starts first: the make, then body types by car count, then cars by length and name.
+	// Matches at a later word come last.
+	want := []string{
+		"Subaru",
+		"Sedan",
+		"SUV",
+		"Subaru Impreza",
+		"Subaru Outback",
+		"BMW 3 Series",
+		"Mini Cooper S",
+	}
+	if got := texts(idx.lookup("s", 10)); !slices.Equal(got, want) {
+		t.Errorf("lookup(s)\n got %q\nwant %q", got, want)
+	}
+
+	if got := texts(idx.lookup("s", 2)); !slices.Equal(got, want[:2]) {
+		t.Errorf("lookup(s, 2) = %q, want %q", got, want[:2])
+	}
+}
+
+func TestSuggestWordStart(t *testing.T) {
+	idx := testSuggestIndex()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"ser", []string{"BMW 3 Series"}},
+		{"SER", []string{"BMW 3 Series"}},
+		{"3 ser", []string{"BMW 3 Series"}},
+		{"  bmw   3 ", []string{"BMW 3 Series"}},
+		{"bmw", []string{"BMW", "BMW 3 Series"}},
+		{"coop", []string{"Mini Cooper S"}},
+		{"eries", nil}, // inside a word
+		{"series 3", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := texts(idx.lookup(tt.query, 10)); !slices.Equal(got, tt.want) && (len(got) > 0 || len(tt.want) > 0) {
+			t.Errorf("lookup(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestServesStaleIndexWhenRebuildFails(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{ds: testDataset()}
+	store, _ := newTestStore(repo) // no refresh, so the index expires
+
+	if got, err := store.Suggest(ctx, "bmw", 10); err != nil || len(got) == 0 {
+		t.Fatalf("Suggest = %q, %v", texts(got), err)
+	}
+
+	// Expire the index and take the upstream down
+	expired := *store.suggestions.Load()
+	expired.builtAt = time.Now().Add(-2 * suggestIndexTTL)
+	store.suggestions.Store(&expired)
+	repo.carsErr = errors.New("upstream is down")
+
+	got, err := store.Suggest(ctx, "bmw", 10)
+	if err != nil || !slices.Contains(texts(got), "BMW 3 Series") {
+		t.Errorf("Suggest with a failed rebuild = %q, %v, want the stale suggestions", texts(got), err)
+	}
+
+	// Without an old index there is nothing to fall back to
+	fresh, _ := newTestStore(repo)
+	if _, err := fresh.Suggest(ctx, "bmw", 10); err == nil {
+		t.Error("Suggest without any index = nil error, want the rebuild error")
+	}
+}
@@ -1,9 +1,11 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,30 +23,40 @@ import (
 
 const DefaultExpiration time.Duration = 0 // 10 * time.Minute
 
-type Item struct {
-	Object     interface{}
+type Item[V any] struct {
+	Object     V
 	Expiration int64
+	Tags       []string
 }
 
-func (item Item) Expired() bool {
+func (item Item[V]) Expired() bool {
 	return time.Now().UnixNano() > item.Expiration
 }
 
-type Cache struct {
-	*cache
+// Cache is a type-safe in-memory key:value store with expiration.
+// Values are stored as V, so reads need no type assertions.
+// Keys may be spread over several independently locked shards, see WithShards.
+type Cache[K comparable, V any] struct {
+	*sharded[K, V]
 }
 
-type cache struct {
+// cache is one shard: a map guarded by its own lock.
+type cache[K comparable, V any] struct {
 	defaultExpiration time.Duration
-	items             map[string]Item
+	items             map[K]Item[V]
 	mu                sync.RWMutex
-	onEvicted         func(string, interface{})
-	janitor           *janitor
+	onEvicted         func(K, V)
+	lru               *lru[K, V] // nil unless the cache has a capacity
+	evictions         atomic.Uint64
+	stats             *statsRecorder[K]
+	stale             time.Duration // how long expired items are kept for GetOrLoad
+	tagger            func(K, V) []string
+	tagged            map[string]map[K]struct{} // tag -> keys, for InvalidateTag
 }
 
 // Add an item to the cache, replacing any existing item. If the duration is 0
 // (DefaultExpiration), the cache's default expiration time is used.
-func (c *cache) Set(k string, x interface{}, d time.Duration) {
+func (c *cache[K, V]) Set(k K, x V, d time.Duration) {
 	// "Inlining" of set
 	var e int64
 	if d == DefaultExpiration {
@@ -53,17 +65,22 @@ func (c *cache) Set(k string, x interface{}, d time.Duration) {
 	if d > 0 {
 		e = time.Now().Add(d).UnixNano()
 	}
+	tags := c.tagsOf(k, x)
 	c.mu.Lock()
-	c.items[k] = Item{
+	c.store(k, Item[V]{
 		Object:     x,
 		Expiration: e,
-	}
+		Tags:       tags,
+	})
+	evicted := c.admit(k, x)
+	c.stats.of(k).sets.Add(1)
 	// TODO: Calls to mu.Unlock are currently not deferred because defer
 	// adds ~200 ns (as of go1.)
 	c.mu.Unlock()
+	c.evicted(evicted)
 }
 
-func (c *cache) set(k string, x interface{}, d time.Duration) {
+func (c *cache[K, V]) set(k K, x V, d time.Duration) []keyAndValue[K, V] {
 	var e int64
 	if d == DefaultExpiration {
 		d = c.defaultExpiration
@@ -71,76 +88,140 @@ func (c *cache) set(k string, x interface{}, d time.Duration) {
 	if d > 0 {
 		e = time.Now().Add(d).UnixNano()
 	}
-	c.items[k] = Item{
+	c.store(k, Item[V]{
 		Object:     x,
 		Expiration: e,
+		Tags:       c.tagsOf(k, x),
+	})
+	c.stats.of(k).sets.Add(1)
+	return c.admit(k, x)
+}
+
+// admit records k as the most recently used key and evicts the least recently
+// used items while the cache is over its capacity. Returns the evicted items
+// for OnEvicted, which must be called after unlocking.
+func (c *cache[K, V]) admit(k K, x V) []keyAndValue[K, V] {
+	if c.lru == nil {
+		return nil
+	}
+	c.lru.add(k, x)
+
+	var evicted []keyAndValue[K, V]
+	for c.lru.over() {
+		oldest, ok := c.lru.oldest()
+		if !ok {
+			break
+		}
+		item := c.items[oldest]
+		c.untag(oldest, item.Tags)
+		delete(c.items, oldest)
+		c.lru.remove(oldest)
+		c.evictions.Add(1)
+		c.stats.of(oldest).evictions.Add(1)
+		if c.onEvicted != nil {
+			evicted = append(evicted, keyAndValue[K, V]{oldest, item.Object})
+		}
+	}
+	return evicted
+}
+
+func (c *cache[K, V]) evicted(items []keyAndValue[K, V]) {
+	for _, v := range items {
+		c.onEvicted(v.key, v.value)
 	}
 }
 
 // Add an item to the cache only if an item doesn't already exist for the given
 // key, or if the existing item has expired. Returns an error otherwise.
-func (c *cache) Add(k string, x interface{}, d time.Duration) error {
+func (c *cache[K, V]) Add(k K, x V, d time.Duration) error {
 	c.mu.Lock()
 	_, found := c.get(k)
 	if found {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s already exists", k)
+		return fmt.Errorf("Item %v already exists", k)
 	}
-	c.set(k, x, d)
+	evicted := c.set(k, x, d)
 	c.mu.Unlock()
+	c.evicted(evicted)
 	return nil
 }
 
 // Set a new value for the cache key only if it already exists, and the existing
 // item hasn't expired. Returns an error otherwise.
-func (c *cache) Replace(k string, x interface{}, d time.Duration) error {
+func (c *cache[K, V]) Replace(k K, x V, d time.Duration) error {
 	c.mu.Lock()
 	_, found := c.get(k)
 	if !found {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s doesn't exist", k)
+		return fmt.Errorf("Item %v doesn't exist", k)
 	}
-	c.set(k, x, d)
+	evicted := c.set(k, x, d)
 	c.mu.Unlock()
+	c.evicted(evicted)
 	return nil
 }
 
-// Get an item from the cache. Returns the item or nil, and a bool indicating
-// whether the key was found.
-func (c *cache) Get(k string) (interface{}, bool) {
+// Get an item from the cache. Returns the item or the zero value of V, and a bool
+// indicating whether the key was found.
+func (c *cache[K, V]) Get(k K) (V, bool) {
+	if c.lru != nil {
+		return c.getAndTouch(k)
+	}
+	var zero V
 	c.mu.RLock()
 	// "Inlining" of get and Expired
 	item, found := c.items[k]
 	if !found {
 		c.mu.RUnlock()
-		return nil, false
+		c.stats.of(k).misses.Add(1)
+		return zero, false
 	}
 	if item.Expiration > 0 {
 		if time.Now().UnixNano() > item.Expiration {
 			c.mu.RUnlock()
-			return nil, false
+			c.stats.of(k).misses.Add(1)
+			return zero, false
 		}
 	}
 	c.mu.RUnlock()
+	c.stats.of(k).hits.Add(1)
 	return item.Object, true
 }
 
-func (c *cache) get(k string) (interface{}, bool) {
+// getAndTouch is Get for a bounded cache. A hit changes the LRU order,
+// so it needs the write lock.
+func (c *cache[K, V]) getAndTouch(k K) (V, bool) {
+	c.mu.Lock()
+	v, found := c.get(k)
+	if found {
+		c.lru.touch(k)
+	}
+	c.mu.Unlock()
+	if found {
+		c.stats.of(k).hits.Add(1)
+	} else {
+		c.stats.of(k).misses.Add(1)
+	}
+	return v, found
+}
+
+func (c *cache[K, V]) get(k K) (V, bool) {
+	var zero V
 	item, found := c.items[k]
 	if !found {
-		return nil, false
+		return zero, false
 	}
 	// "Inlining" of Expired
 	if item.Expiration > 0 {
 		if time.Now().UnixNano() > item.Expiration {
-			return nil, false
+			return zero, false
 		}
 	}
 	return item.Object, true
 }
 
 // Delete an item from the cache. Does nothing if the key is not in the cache.
-func (c *cache) Delete(k string) {
+func (c *cache[K, V]) Delete(k K) {
 	c.mu.Lock()
 	v, evicted := c.delete(k)
 	c.mu.Unlock()
@@ -149,33 +230,40 @@ func (c *cache) Delete(k string) {
 	}
 }
 
-func (c *cache) delete(k string) (interface{}, bool) {
-	if c.onEvicted != nil {
-		if v, found := c.items[k]; found {
-			delete(c.items, k)
-			return v.Object, true
-		}
+func (c *cache[K, V]) delete(k K) (V, bool) {
+	var zero V
+	if c.lru != nil {
+		c.lru.remove(k)
 	}
+	v, found := c.items[k]
+	if !found {
+		return zero, false
+	}
+	c.untag(k, v.Tags)
 	delete(c.items, k)
-	return nil, false
+	if c.onEvicted != nil {
+		return v.Object, true
+	}
+	return zero, false
 }
 
-type keyAndValue struct {
-	key   string
-	value interface{}
+type keyAndValue[K comparable, V any] struct {
+	key   K
+	value V
 }
 
 // Delete all expired items from the cache.
-func (c *cache) DeleteExpired() {
-	var evictedItems []keyAndValue
+func (c *cache[K, V]) DeleteExpired() {
+	var evictedItems []keyAndValue[K, V]
 	now := time.Now().UnixNano()
 	c.mu.Lock()
 	for k, v := range c.items {
-		// "Inlining" of expired
-		if v.Expiration > 0 && now > v.Expiration {
+		// "Inlining" of expired, past the stale window
+		if v.Expiration > 0 && now > v.Expiration+int64(c.stale) {
 			ov, evicted := c.delete(k)
+			c.stats.of(k).expirations.Add(1)
 			if evicted {
-				evictedItems = append(evictedItems, keyAndValue{k, ov})
+				evictedItems = append(evictedItems, keyAndValue[K, V]{k, ov})
 			}
 		}
 	}
@@ -188,7 +276,7 @@ func (c *cache) DeleteExpired() {
 // Sets an (optional) function that is called with the key and value when an
 // item is evicted from the cache. (Including when it is deleted manually, but
 // not when it is overwritten.) Set to nil to disable.
-func (c *cache) OnEvicted(f func(string, interface{})) {
+func (c *cache[K, V]) OnEvicted(f func(K, V)) {
 	c.mu.Lock()
 	c.onEvicted = f
 	c.mu.Unlock()
@@ -196,7 +284,7 @@ func (c *cache) OnEvicted(f func(string, interface{})) {
 
 // Returns the number of items in the cache. This may include items that have
 // expired, but have not yet been cleaned up.
-func (c *cache) ItemCount() int {
+func (c *cache[K, V]) ItemCount() int {
 	c.mu.RLock()
 	n := len(c.items)
 	c.mu.RUnlock()
@@ -204,20 +292,38 @@ func (c *cache) ItemCount() int {
 }
 
 // Delete all items from the cache.
-func (c *cache) Flush() {
+func (c *cache[K, V]) Flush() {
 	c.mu.Lock()
-	c.items = map[string]Item{}
+	c.items = map[K]Item[V]{}
+	c.tagged = nil
+	if c.lru != nil {
+		c.lru.reset()
+	}
 	c.mu.Unlock()
 }
 
+// Returns the number of items evicted to keep the cache within its capacity.
+// Expired and manually deleted items are not counted.
+func (c *cache[K, V]) Evictions() uint64 {
+	return c.evictions.Load()
+}
+
 // JENITOR
 
 type janitor struct {
 	Interval time.Duration
-	stop     chan bool
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// sweeper is what the janitor needs from a cache, so one janitor type serves every instantiation.
+type sweeper interface {
+	DeleteExpired()
 }
 
-func (j *janitor) Run(c *cache) {
+func (j *janitor) Run(c sweeper) {
+	defer close(j.done)
 	ticker := time.NewTicker(j.Interval)
 	for {
 		select {
@@ -230,41 +336,60 @@ func (j *janitor) Run(c *cache) {
 	}
 }
 
-func stopJanitor(c *Cache) {
-	c.janitor.stop <- true
+// Stop stops the janitor and waits until its goroutine has exited, or ctx is done
+// (e.g. a sweep of a huge cache outlasts the shutdown timeout). Safe to call more than once.
+func (j *janitor) Stop(ctx context.Context) error {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func stopJanitor[K comparable, V any](c *Cache[K, V]) {
+	_ = c.janitor.Stop(context.Background())
 }
 
-func runJanitor(c *cache, ci time.Duration) {
+func runJanitor[K comparable, V any](c *sharded[K, V], ci time.Duration) {
 	j := &janitor{
 		Interval: ci,
-		stop:     make(chan bool),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	c.janitor = j
 	go j.Run(c)
 }
 
-func newCache(de time.Duration, m map[string]Item) *cache {
+func newCache[K comparable, V any](de time.Duration, m map[K]Item[V]) *cache[K, V] {
 	if de == 0 {
 		de = -1
 	}
-	c := &cache{
+	c := &cache[K, V]{
 		defaultExpiration: de,
 		items:             m,
 	}
 	return c
 }
 
-func newCacheWithJanitor(de time.Duration, ci time.Duration, m map[string]Item) *Cache {
-	c := newCache(de, m)
+func newCacheWithJanitor[K comparable, V any](de time.Duration, ci time.Duration, opts ...Option[K, V]) *Cache[K, V] {
+	o := options[K, V]{shards: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := newSharded(de, o)
 	// This trick ensures that the janitor goroutine (which--granted it
 	// was enabled--is running DeleteExpired on c forever) does not keep
 	// the returned C object from being garbage collected. When it is
 	// garbage collected, the finalizer stops the janitor goroutine, after
 	// which c can be collected.
-	C := &Cache{c}
+	C := &Cache[K, V]{c}
 	if ci > 0 {
 		runJanitor(c, ci)
-		runtime.SetFinalizer(C, stopJanitor)
+		runtime.SetFinalizer(C, stopJanitor[K, V])
 	}
 	return C
 }
@@ -274,7 +399,7 @@ func newCacheWithJanitor(de time.Duration, ci time.Duration, m map[string]Item)
 // the items in the cache never expire (by default), and must be deleted
 // manually. If the cleanup interval is less than one, expired items are not
 // deleted from the cache before calling c.DeleteExpired().
-func New(defaultExpiration, cleanupInterval time.Duration) *Cache {
-	items := make(map[string]Item)
-	return newCacheWithJanitor(defaultExpiration, cleanupInterval, items)
+// Options may bound the cache size (WithMaxEntries, WithMaxBytes) or shard it (WithShards).
+func New[K comparable, V any](defaultExpiration, cleanupInterval time.Duration, opts ...Option[K, V]) *Cache[K, V] {
+	return newCacheWithJanitor(defaultExpiration, cleanupInterval, opts...)
 }
@@ -0,0 +1,486 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchCar is shaped like domain.Car: a few strings and ints, about 200 bytes
+type benchCar struct {
+	ID           int
+	Name         string
+	Year         int
+	Image        string
+	Engine       string
+	HP           int
+	Gearbox      string
+	Transmission string
+	Drivetrain   string
+	Vendor       string
+	Country      string
+	Founded      int
+	Category     string
+}
+
+const benchKeys = 1024
+
+func benchData() ([]string, []benchCar) {
+	keys := make([]string, benchKeys)
+	cars := make([]benchCar, benchKeys)
+	for i := range keys {
+		keys[i] = "car:" + strconv.Itoa(i)
+		cars[i] = benchCar{ID: i, Name: "Model " + strconv.Itoa(i), Year: 2020, HP: 300, Vendor: "BMW"}
+	}
+	return keys, cars
+}
+
+// ifaceCache is the Set/Get path of the cache before it was made generic,
+// values are stored as interface{} and read back with a type assertion
+type ifaceCache struct {
+	items map[string]ifaceItem
+	mu    sync.RWMutex
+}
+
+type ifaceItem struct {
+	Object     interface{}
+	Expiration int64
+}
+
+func (c *ifaceCache) Set(k string, x interface{}, d time.Duration) {
+	var e int64
+	if d > 0 {
+		e = time.Now().Add(d).UnixNano()
+	}
+	c.mu.Lock()
+	c.items[k] = ifaceItem{Object: x, Expiration: e}
+	c.mu.Unlock()
+}
+
+func (c *ifaceCache) Get(k string) (interface{}, bool) {
+	c.mu.RLock()
+	item, found := c.items[k]
+	if !found {
+		c.mu.RUnlock()
+		return nil, false
+	}
+	if item.Expiration > 0 && time.Now().UnixNano() > item.Expiration {
+		c.mu.RUnlock()
+		return nil, false
+	}
+	c.mu.RUnlock()
+	return item.Object, true
+}
+
+func BenchmarkGet(b *testing.B) {
+	keys, cars := benchData()
+
+	b.Run("generic", func(b *testing.B) {
+		c := New[string, benchCar](time.Hour, 0)
+		for i := range keys {
+			c.Set(keys[i], cars[i], DefaultExpiration)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, ok := c.Get(keys[i%benchKeys]); !ok {
+				b.Fatal("miss")
+			}
+		}
+	})
+
+	b.Run("interface", func(b *testing.B) {
+		c := &ifaceCache{items: make(map[string]ifaceItem)}
+		for i := range keys {
+			c.Set(keys[i], cars[i], time.Hour)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, ok := c.Get(keys[i%benchKeys])
+			if !ok {
+				b.Fatal("miss")
+			}
+			if _, ok := v.(benchCar); !ok {
+				b.Fatal("wrong type")
+			}
+		}
+	})
+}
+
+func BenchmarkSet(b *testing.B) {
+	keys, cars := benchData()
+
+	b.Run("generic", func(b *testing.B) {
+		c := New[string, benchCar](time.Hour, 0)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.Set(keys[i%benchKeys], cars[i%benchKeys], DefaultExpiration)
+		}
+	})
+
+	b.Run("interface", func(b *testing.B) {
+		c := &ifaceCache{items: make(map[string]ifaceItem)}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.Set(keys[i%benchKeys], cars[i%benchKeys], time.Hour)
+		}
+	})
+}
+
+func TestShardLimitsAddUpToCapacity(t *testing.T) {
+	tests := []struct {
+		shards, maxEntries int
+		maxBytes           int64
+		wantShards         int
+	}{
+		{shards: 16, maxEntries: 100, wantShards: 16},
+		{shards: 16, maxEntries: 1000, maxBytes: 1 << 20, wantShards: 16},
+		{shards: 5, maxEntries: 7, wantShards: 4},
+		{shards: 16, maxEntries: 3, wantShards: 2},
+		{shards: 8, maxEntries: 1, wantShards: 1},
+		{shards: 8, maxBytes: 6, wantShards: 4},
+	}
+
+	for _, tt := range tests {
+		c := New[string, benchCar](0, 0,
+			WithShards[string, benchCar](tt.shards),
+			WithMaxEntries[string, benchCar](tt.maxEntries),
+			WithMaxBytes(tt.maxBytes, func(string, benchCar) int64 { return 1 }),
+		)
+
+		if got := len(c.shards); got != tt.wantShards {
+			t.Errorf("shards=%d entries=%d bytes=%d: %d shards, want %d",
+				tt.shards, tt.maxEntries, tt.maxBytes, got, tt.wantShards)
+		}
+
+		var entries int
+		var bytes int64
+		for _, s := range c.shards {
+			if (tt.maxEntries > 0 && s.lru.maxEntries < 1) || (tt.maxBytes > 0 && s.lru.maxBytes < 1) {
+				t.Errorf("shards=%d entries=%d bytes=%d: a shard got no capacity",
+					tt.shards, tt.maxEntries, tt.maxBytes)
+			}
+			entries += s.lru.maxEntries
+			bytes += s.lru.maxBytes
+		}
+		if entries != tt.maxEntries || bytes != tt.maxBytes {
+			t.Errorf("shards=%d entries=%d bytes=%d: shard limits add up to %d entries, %d bytes",
+				tt.shards, tt.maxEntries, tt.maxBytes, entries, bytes)
+		}
+	}
+}
+
+// BenchmarkParallel measures lock contention: 3 reads to 1 write over 4096 keys from every goroutine,
+// with one lock for the whole cache and with 16 shards. A bounded cache takes the write lock on Get as well.
+func BenchmarkParallel(b *testing.B) {
+	const keys = 4096
+
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "car:" + strconv.Itoa(i)
+	}
+
+	for _, bounded := range []bool{false, true} {
+		for _, shards := range []int{1, 16} {
+			name := "plain"
+			if bounded {
+				name = "lru"
+			}
+			name += "/shards=" + strconv.Itoa(shards)
+
+			b.Run(name, func(b *testing.B) {
+				opts := []Option[string, benchCar]{WithShards[string, benchCar](shards)}
+				if bounded {
+					opts = append(opts, WithMaxEntries[string, benchCar](keys))
+				}
+				c := New[string, benchCar](time.Hour, 0, opts...)
+				for i, k := range names {
+					c.Set(k, benchCar{ID: i}, DefaultExpiration)
+				}
+
+				var seed atomic.Uint64
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					// Every goroutine walks the keys from its own offset
+					i := int(seed.Add(997))
+					for pb.Next() {
+						k := names[i%keys]
+						if i%4 == 0 {
+							c.Set(k, benchCar{ID: i}, DefaultExpiration)
+						} else {
+							c.Get(k)
+						}
+						i++
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestStaleHitsShareOneRefresh(t *testing.T) {
+	c := New[string, int](10*time.Millisecond, 0, WithStaleWindow[string, int](time.Hour))
+	c.Set("k", 1, DefaultExpiration)
+	time.Sleep(20 * time.Millisecond)
+
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func(ctx context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 2, nil
+	}
+
+	for range 100 {
+		if v, err := c.GetOrLoad(context.Background(), "k", load); v != 1 || err != nil {
+			t.Fatalf("GetOrLoad = %d, %v, want the stale 1", v, err)
+		}
+	}
+
+	// The first stale hit started the refresh, the other 99 found it running
+	waitFor(t, "the refresh to start", func() bool { return loads.Load() > 0 })
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d while the refresh is blocked, want 1", n)
+	}
+	if st := c.Stats()[""]; st.StaleHits != 100 {
+		t.Errorf("stale hits = %d, want 100", st.StaleHits)
+	}
+
+	close(release)
+	waitFor(t, "the stale item to be refreshed", func() bool {
+		v, ok := c.Get("k")
+		return ok && v == 2
+	})
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1", n)
+	}
+}
+
+func TestConcurrentMissesShareOneLoad(t *testing.T) {
+	const callers = 50
+
+	c := New[string, int](time.Hour, 0)
+
+	var (
+		loads   atomic.Int32
+		wrong   atomic.Int32
+		wg      sync.WaitGroup
+		release = make(chan struct{})
+	)
+	load := func(ctx context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad(context.Background(), "k", load); v != 42 || err != nil {
+				wrong.Add(1)
+			}
+		}()
+	}
+
+	waitFor(t, "every caller to join the load", func() bool { return c.flight.Waiters("k") == callers })
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d for %d concurrent misses, want 1", n, callers)
+	}
+	if n := wrong.Load(); n > 0 {
+		t.Errorf("%d callers didn't get the loaded value", n)
+	}
+	if v, ok := c.Get("k"); !ok || v != 42 {
+		t.Errorf("Get = %d, %v, want the loaded value cached", v, ok)
+	}
+}
+
+// waitFor polls cond for up to a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// taggedCar is tagged with its make and body type, a car without a make gets no tags
+type taggedCar struct {
+	Make, Body string
+}
+
+func carTags(_ string, car taggedCar) []string {
+	if car.Make == "" {
+		return nil
+	}
+	return []string{"make:" + car.Make, "body:" + car.Body}
+}
+
+func newTaggedCache(opts ...Option[string, taggedCar]) *Cache[string, taggedCar] {
+	opts = append(opts, WithTags(carTags), WithShards[string, taggedCar](4))
+	return New[string, taggedCar](time.Hour, 0, opts...)
+}
+
+// tagIndex returns the sorted keys behind every tag of the index, over all shards
+func tagIndex(c *Cache[string, taggedCar]) map[string][]string {
+	index := make(map[string][]string)
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for tag, keys := range s.tagged {
+			for k := range keys {
+				index[tag] = append(index[tag], k)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	for _, keys := range index {
+		slices.Sort(keys)
+	}
+	return index
+}
+
+func TestInvalidateTagDropsTaggedOnly(t *testing.T) {
+	c := newTaggedCache()
+	var dropped []string
+	c.OnEvicted(func(k string, _ taggedCar) { dropped = append(dropped, k) })
+
+	c.Set("car:1", taggedCar{"bmw", "sedan"}, DefaultExpiration)
+	c.Set("car:2", taggedCar{"bmw", "suv"}, DefaultExpiration)
+	c.Set("car:3", taggedCar{"audi", "sedan"}, DefaultExpiration)
+	c.Set("car:4", taggedCar{}, DefaultExpiration)
+
+	if n := c.InvalidateTag("make:bmw"); n != 2 {
+		t.Errorf("InvalidateTag = %d, want 2", n)
+	}
+
+	for k, want := range map[string]bool{"car:1": false, "car:2": false, "car:3": true, "car:4": true} {
+		if _, ok := c.Get(k); ok != want {
+			t.Errorf("%s cached = %v, want %v", k, ok, want)
+		}
+	}
+	slices.Sort(dropped)
+	if !slices.Equal(dropped, []string{"car:1", "car:2"}) {
+		t.Errorf("OnEvicted got %q, want the invalidated cars", dropped)
+	}
+	if n := c.Stats()[""].Invalidated; n != 2 {
+		t.Errorf("invalidated = %d, want 2", n)
+	}
+
+	// The other tags of the dropped cars are gone from the index too
+	want := map[string][]string{"make:audi": {"car:3"}, "body:sedan": {"car:3"}}
+	if got := tagIndex(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("tag index = %v, want %v", got, want)
+	}
+
+	if n := c.InvalidateTag("make:unknown"); n != 0 {
+		t.Errorf("InvalidateTag of an unknown tag = %d, want 0", n)
+	}
+}
+
+func TestTagsFollowReplace(t *testing.T) {
+	c := newTaggedCache()
+	c.Set("car:1", taggedCar{"bmw", "sedan"}, DefaultExpiration)
+
+	if err := c.Replace("car:1", taggedCar{"audi", "sedan"}, DefaultExpiration); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.InvalidateTag("make:bmw"); n != 0 {
+		t.Errorf("old tag dropped %d items after Replace, want 0", n)
+	}
+	if _, ok := c.Get("car:1"); !ok {
+		t.Fatal("car dropped by its old tag")
+	}
+
+	c.Set("car:1", taggedCar{"mini", "hatch"}, DefaultExpiration)
+	want := map[string][]string{"make:mini": {"car:1"}, "body:hatch": {"car:1"}}
+	if got := tagIndex(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("tag index after Set = %v, want %v", got, want)
+	}
+}
+
+func TestTagsRestoredFromSnapshot(t *testing.T) {
+	src := newTaggedCache()
+	src.Set("car:1", taggedCar{"bmw", "sedan"}, DefaultExpiration)
+	src.Set("car:2", taggedCar{"audi", "suv"}, DefaultExpiration)
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTaggedCache()
+	if loaded, _, err := dst.Load(&buf); err != nil || loaded != 2 {
+		t.Fatalf("Load = %d, %v", loaded, err)
+	}
+	if got, want := tagIndex(dst), tagIndex(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored tag index = %v, want %v", got, want)
+	}
+	if n := dst.InvalidateTag("make:bmw"); n != 1 {
+		t.Errorf("InvalidateTag on restored items = %d, want 1", n)
+	}
+}
+
+func TestTagIndexCleanedUp(t *testing.T) {
+	tests := []struct {
+		name string
+		drop func(c *Cache[string, taggedCar])
+		opts []Option[string, taggedCar]
+	}{
+		{
+			name: "delete",
+			drop: func(c *Cache[string, taggedCar]) { c.Delete("car:1") },
+		},
+		{
+			name: "expiry",
+			drop: func(c *Cache[string, taggedCar]) {
+				time.Sleep(20 * time.Millisecond)
+				c.DeleteExpired()
+			},
+		},
+		{
+			name: "eviction",
+			drop: func(c *Cache[string, taggedCar]) { c.Set("car:2", taggedCar{}, DefaultExpiration) },
+			opts: []Option[string, taggedCar]{WithMaxEntries[string, taggedCar](1)},
+		},
+		{
+			name: "flush",
+			drop: func(c *Cache[string, taggedCar]) { c.Flush() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTaggedCache(tt.opts...)
+			c.Set("car:1", taggedCar{"bmw", "sedan"}, 10*time.Millisecond)
+			if len(tagIndex(c)) == 0 {
+				t.Fatal("item wasn't tagged")
+			}
+
+			tt.drop(c)
+
+			if _, ok := c.Get("car:1"); ok {
+				t.Fatal("item is still cached")
+			}
+			if got := tagIndex(c); len(got) > 0 {
+				t.Errorf("tag index = %v, want empty", got)
+			}
+		})
+	}
+}
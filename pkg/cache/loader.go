@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// A background refresh of a stale item gives up after this long
+const refreshTimeout = 30 * time.Second
+
+// GetOrLoad returns the cached item for k, or calls load and caches its result
+// with the default expiration. Concurrent calls for the same key share one load.
+//
+// With WithStaleWindow, an item that expired less than the window ago is returned
+// right away and a single background load refreshes it, so an expiry doesn't turn
+// into a latency spike for every caller. The background load keeps the values of ctx,
+// but not its cancellation, and is bounded by refreshTimeout.
+func (s *sharded[K, V]) GetOrLoad(ctx context.Context, k K, load func(ctx context.Context) (V, error)) (V, error) {
+	v, fresh, found := s.shard(k).lookup(k)
+	switch {
+	case found && fresh:
+		s.stats.of(k).hits.Add(1)
+		return v, nil
+	case found:
+		s.stats.of(k).staleHits.Add(1)
+		// One refresh per key at a time, not a goroutine per stale hit
+		if _, busy := s.refreshing.LoadOrStore(k, struct{}{}); !busy {
+			go s.refresh(context.WithoutCancel(ctx), k, load)
+		}
+		return v, nil
+	}
+
+	s.stats.of(k).misses.Add(1)
+	return s.load(ctx, k, load)
+}
+
+func (s *sharded[K, V]) refresh(ctx context.Context, k K, load func(ctx context.Context) (V, error)) {
+	defer s.refreshing.Delete(k)
+
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	_, _ = s.load(ctx, k, load)
+}
+
+func (s *sharded[K, V]) load(ctx context.Context, k K, load func(ctx context.Context) (V, error)) (V, error) {
+	v, err, _ := s.flight.Do(ctx, k, func(ctx context.Context) (V, error) {
+		v, err := load(ctx)
+		if err != nil {
+			return v, err
+		}
+		s.Set(k, v, DefaultExpiration)
+		return v, nil
+	})
+	return v, err
+}
+
+// lookup is Get that also returns items within the stale window, with fresh = false.
+func (c *cache[K, V]) lookup(k K) (v V, fresh, found bool) {
+	// A hit changes the LRU order of a bounded cache, so it needs the write lock
+	if c.lru != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	} else {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	item, found := c.items[k]
+	if !found {
+		return v, false, false
+	}
+
+	now := time.Now().UnixNano()
+	if item.Expiration > 0 && now > item.Expiration {
+		if now > item.Expiration+int64(c.stale) {
+			return v, false, false
+		}
+		return item.Object, false, true
+	}
+
+	if c.lru != nil {
+		c.lru.touch(k)
+	}
+	return item.Object, true, true
+}
@@ -0,0 +1,106 @@
+package cache
+
+// lru keeps the recency order of keys for a size-bounded cache.
+// It isn't safe for concurrent use, the cache calls it under its own lock.
+type lru[K comparable, V any] struct {
+	maxEntries int               // 0 means no limit
+	maxBytes   int64             // 0 means no limit
+	sizeOf     func(K, V) int64  // approximate size of an item, used with maxBytes
+	nodes      map[K]*lruNode[K] // the list is intrusive, so a touch doesn't allocate
+	head, tail *lruNode[K]       // head is the most recently used
+	bytes      int64
+}
+
+type lruNode[K comparable] struct {
+	key        K
+	size       int64
+	prev, next *lruNode[K]
+}
+
+func newLRU[K comparable, V any]() *lru[K, V] {
+	return &lru[K, V]{nodes: make(map[K]*lruNode[K])}
+}
+
+// add inserts k as the most recently used key or updates its size.
+func (l *lru[K, V]) add(k K, v V) {
+	var size int64
+	if l.sizeOf != nil {
+		size = l.sizeOf(k, v)
+	}
+
+	n, found := l.nodes[k]
+	if !found {
+		n = &lruNode[K]{key: k}
+		l.nodes[k] = n
+	} else {
+		l.unlink(n)
+		l.bytes -= n.size
+	}
+
+	n.size = size
+	l.bytes += size
+	l.pushFront(n)
+}
+
+// touch marks k as the most recently used key.
+func (l *lru[K, V]) touch(k K) {
+	if n, found := l.nodes[k]; found && n != l.head {
+		l.unlink(n)
+		l.pushFront(n)
+	}
+}
+
+func (l *lru[K, V]) remove(k K) {
+	if n, found := l.nodes[k]; found {
+		l.unlink(n)
+		l.bytes -= n.size
+		delete(l.nodes, k)
+	}
+}
+
+// over reports whether the cache holds more than its capacity.
+func (l *lru[K, V]) over() bool {
+	return (l.maxEntries > 0 && len(l.nodes) > l.maxEntries) ||
+		(l.maxBytes > 0 && l.bytes > l.maxBytes)
+}
+
+// oldest returns the least recently used key.
+func (l *lru[K, V]) oldest() (K, bool) {
+	if l.tail == nil {
+		var zero K
+		return zero, false
+	}
+	return l.tail.key, true
+}
+
+func (l *lru[K, V]) reset() {
+	l.nodes = make(map[K]*lruNode[K])
+	l.head, l.tail = nil, nil
+	l.bytes = 0
+}
+
+func (l *lru[K, V]) pushFront(n *lruNode[K]) {
+	n.prev = nil
+	n.next = l.head
+	if l.head != nil {
+		l.head.prev = n
+	}
+	l.head = n
+	if l.tail == nil {
+		l.tail = n
+	}
+}
+
+func (l *lru[K, V]) unlink(n *lruNode[K]) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		l.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		l.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// evictionLog records the keys passed to OnEvicted
+type evictionLog struct {
+	keys []string
+}
+
+func (l *evictionLog) record(k string, _ string) {
+	l.keys = append(l.keys, k)
+}
+
+func present(c *Cache[string, string], keys ...string) []string {
+	var out []string
+	for _, k := range keys {
+		if _, ok := c.Get(k); ok {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func TestMaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, string](0, 0, WithMaxEntries[string, string](3))
+	var log evictionLog
+	c.OnEvicted(log.record)
+
+	c.Set("a", "1", DefaultExpiration)
+	c.Set("b", "2", DefaultExpiration)
+	c.Set("c", "3", DefaultExpiration)
+	c.Get("a") // a is used again, b becomes the oldest
+	c.Set("d", "4", DefaultExpiration)
+
+	if !slices.Equal(log.keys, []string{"b"}) {
+		t.Errorf("evicted %q, want [b]", log.keys)
+	}
+	if n := c.Evictions(); n != 1 {
+		t.Errorf("Evictions = %d, want 1", n)
+	}
+
+	// Overwriting a key is not an eviction and refreshes it as well
+	c.Set("c", "33", DefaultExpiration)
+	c.Set("e", "5", DefaultExpiration)
+
+	if !slices.Equal(log.keys, []string{"b", "a"}) {
+		t.Errorf("evicted %q, want [b a]", log.keys)
+	}
+	if got := present(c, "a", "b", "c", "d", "e"); !slices.Equal(got, []string{"c", "d", "e"}) {
+		t.Errorf("cache holds %q, want [c d e]", got)
+	}
+	if n := c.Evictions(); n != 2 {
+		t.Errorf("Evictions = %d, want 2", n)
+	}
+}
+
+func TestMaxBytesEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, string](0, 0, WithMaxBytes(10, func(_ string, v string) int64 { return int64(len(v)) }))
+	var log evictionLog
+	c.OnEvicted(log.record)
+
+	c.Set("a", "aaaa", DefaultExpiration)
+	c.Set("b", "bbbb", DefaultExpiration)
+	c.Get("a")
+	c.Set("c", "cccc", DefaultExpiration) // 12 bytes, b is the oldest
+
+	if !slices.Equal(log.keys, []string{"b"}) {
+		t.Errorf("evicted %q, want [b]", log.keys)
+	}
+
+	// A big item pushes out as many old ones as it takes
+	c.Set("d", strings.Repeat("d", 8), DefaultExpiration)
+
+	if !slices.Equal(log.keys, []string{"b", "a", "c"}) {
+		t.Errorf("evicted %q, want [b a c]", log.keys)
+	}
+	if got := present(c, "a", "b", "c", "d"); !slices.Equal(got, []string{"d"}) {
+		t.Errorf("cache holds %q, want [d]", got)
+	}
+	if n := c.Evictions(); n != 3 {
+		t.Errorf("Evictions = %d, want 3", n)
+	}
+
+	// Growing an item in place counts its new size
+	c.Set("e", "ee", DefaultExpiration)
+	c.Set("e", strings.Repeat("e", 4), DefaultExpiration)
+	if got := present(c, "d", "e"); !slices.Equal(got, []string{"e"}) {
+		t.Errorf("cache holds %q, want [e] after e grew past the limit", got)
+	}
+}
+
+func TestDeleteIsNotAnEviction(t *testing.T) {
+	c := New[string, string](0, 0, WithMaxEntries[string, string](2))
+	var log evictionLog
+	c.OnEvicted(log.record)
+
+	c.Set("a", "1", DefaultExpiration)
+	c.Set("b", "2", DefaultExpiration)
+	c.Delete("a")
+	c.Set("c", "3", DefaultExpiration) // fits in the freed slot
+
+	if !slices.Equal(log.keys, []string{"a"}) {
+		t.Errorf("OnEvicted got %q, want only the deleted a", log.keys)
+	}
+	if n := c.Evictions(); n != 0 {
+		t.Errorf("Evictions = %d, want 0, nothing was dropped for capacity", n)
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import "time"
+
+// Option configures optional cache behavior, e.g. capacity or sharding.
+type Option[K comparable, V any] func(*options[K, V])
+
+type options[K comparable, V any] struct {
+	shards     int
+	maxEntries int
+	maxBytes   int64
+	sizeOf     func(K, V) int64
+	group      func(K) string
+	stale      time.Duration
+	tagger     func(K, V) []string
+}
+
+// WithMaxEntries bounds the cache to n items. When a new item doesn't fit,
+// the least recently used items are evicted and passed to OnEvicted.
+// n < 1 means no limit.
+func WithMaxEntries[K comparable, V any](n int) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.maxEntries = max(n, 0)
+	}
+}
+
+// WithMaxBytes bounds the approximate memory used by the cache items.
+// sizeOf estimates the size of one item in bytes. n < 1 means no limit.
+func WithMaxBytes[K comparable, V any](n int64, sizeOf func(K, V) int64) Option[K, V] {
+	return func(o *options[K, V]) {
+		if n < 1 || sizeOf == nil {
+			return
+		}
+		o.maxBytes = n
+		o.sizeOf = sizeOf
+	}
+}
+
+// WithShards spreads keys over n independently locked shards (rounded up to a power of two),
+// so concurrent operations on different keys rarely wait for each other.
+// The capacity is split between the shards so their limits add up to exactly the configured one,
+// and LRU order is kept per shard. A capacity smaller than n leaves fewer shards, at least one
+// entry each. n < 2 means a single shard.
+func WithShards[K comparable, V any](n int) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.shards = max(n, 1)
+	}
+}
+
+// WithStatsGroups sets how keys are grouped in Stats, e.g. by a key prefix like "car:".
+func WithStatsGroups[K comparable, V any](group func(K) string) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.group = group
+	}
+}
+
+// WithStaleWindow keeps items for d after they expire. GetOrLoad serves such a stale
+// item right away and refreshes it in the background, while Get treats it as expired.
+func WithStaleWindow[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.stale = max(d, 0)
+	}
+}
+
+// WithTags tags every item with the tags returned by tagger when it is set,
+// so related items can be dropped together with InvalidateTag.
+func WithTags[K comparable, V any](tagger func(K, V) []string) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.tagger = tagger
+	}
+}
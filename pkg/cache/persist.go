@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshot format: a magic header, then one record per item:
+//
+//	uint32 length | uint32 crc32 of payload | payload (gob encoded record)
+//
+// Every record is encoded on its own, so a corrupt record is skipped
+// without losing the ones after it.
+//
+// Keys and values are encoded with encoding/gob. Concrete types round-trip
+// as they are, but values stored behind interface types (including fields of
+// interface type inside V) must be registered with gob.Register before
+// Save and Load, like for any other gob stream.
+
+const snapshotMagic = "GOCACHE1"
+
+// maxRecordSize protects Load from allocating a huge buffer for a corrupt length
+const maxRecordSize = 64 << 20
+
+type record[K comparable, V any] struct {
+	Key        K
+	Object     V
+	Expiration int64
+}
+
+// Save writes all items that haven't expired to w, with their expiration time.
+func (s *sharded[K, V]) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+
+	var (
+		payload bytes.Buffer
+		header  [8]byte
+	)
+
+	now := time.Now().UnixNano()
+
+	for _, c := range s.shards {
+		for _, r := range c.records(now) {
+			payload.Reset()
+			if err := gob.NewEncoder(&payload).Encode(r); err != nil {
+				return fmt.Errorf("encode item %v: %w", r.Key, err)
+			}
+
+			binary.BigEndian.PutUint32(header[0:4], uint32(payload.Len()))
+			binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+
+			if _, err := bw.Write(header[:]); err != nil {
+				return err
+			}
+			if _, err := bw.Write(payload.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// records copies the live items of a shard, so encoding happens without holding the lock.
+func (c *cache[K, V]) records(now int64) []record[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	records := make([]record[K, V], 0, len(c.items))
+	for k, v := range c.items {
+		if v.Expiration > 0 && now > v.Expiration {
+			continue
+		}
+		records = append(records, record[K, V]{Key: k, Object: v.Object, Expiration: v.Expiration})
+	}
+	return records
+}
+
+// Load adds the items saved by Save that haven't expired since. Items that
+// already exist in the cache are kept. Corrupt records are skipped and counted.
+// If a record length is corrupt the rest of the stream can't be trusted,
+// so Load stops there and returns what it has loaded with an error.
+func (s *sharded[K, V]) Load(r io.Reader) (loaded, skipped int, err error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return 0, 0, fmt.Errorf("read header: %w", err)
+	}
+	if string(magic) != snapshotMagic {
+		return 0, 0, errors.New("not a cache snapshot")
+	}
+
+	var header [8]byte
+
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return loaded, skipped, nil
+			}
+			return loaded, skipped, fmt.Errorf("read record header: %w", err)
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		sum := binary.BigEndian.Uint32(header[4:8])
+
+		if size > maxRecordSize {
+			return loaded, skipped + 1, fmt.Errorf("corrupt record length %d", size)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return loaded, skipped + 1, fmt.Errorf("read record: %w", err)
+		}
+
+		if crc32.ChecksumIEEE(payload) != sum {
+			skipped++
+			continue
+		}
+
+		var rec record[K, V]
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+			skipped++
+			continue
+		}
+
+		if rec.Expiration > 0 && time.Now().UnixNano() > rec.Expiration {
+			skipped++
+			continue
+		}
+
+		if s.shard(rec.Key).restore(rec) {
+			loaded++
+		}
+	}
+}
+
+// restore adds a saved item with its original expiration, unless the key is already cached.
+func (c *cache[K, V]) restore(r record[K, V]) bool {
+	c.mu.Lock()
+	if _, found := c.get(r.Key); found {
+		c.mu.Unlock()
+		return false
+	}
+	// Tags aren't saved, they are derived from the value again, see WithTags
+	c.store(r.Key, Item[V]{
+		Object:     r.Object,
+		Expiration: r.Expiration,
+		Tags:       c.tagsOf(r.Key, r.Object),
+	})
+	evicted := c.admit(r.Key, r.Object)
+	c.mu.Unlock()
+	c.evicted(evicted)
+	return true
+}
+
+// SaveFile saves the cache to path. It writes a temporary file first and renames it,
+// so a crash in the middle never leaves a truncated snapshot behind.
+func (s *sharded[K, V]) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if err := s.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads a snapshot written by SaveFile, see Load.
+func (s *sharded[K, V]) LoadFile(path string) (loaded, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	return s.Load(f)
+}
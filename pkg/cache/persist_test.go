@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordOffsets returns where the payload of every record in a snapshot starts
+func recordOffsets(t *testing.T, snapshot []byte) []int {
+	t.Helper()
+
+	var offsets []int
+	for pos := len(snapshotMagic); pos < len(snapshot); {
+		size := int(binary.BigEndian.Uint32(snapshot[pos : pos+4]))
+		offsets = append(offsets, pos+8)
+		pos += 8 + size
+	}
+	return offsets
+}
+
+func save(t *testing.T, c *Cache[string, string]) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveLoadKeepsExpiration(t *testing.T) {
+	src := New[string, string](time.Hour, 0)
+	src.Set("expiring", "1", DefaultExpiration)
+	src.Set("forever", "2", -1) // never expires
+
+	dst := New[string, string](time.Minute, 0)
+	loaded, skipped, err := dst.Load(bytes.NewReader(save(t, src)))
+	if err != nil || loaded != 2 || skipped != 0 {
+		t.Fatalf("Load = %d, %d, %v, want 2 loaded", loaded, skipped, err)
+	}
+
+	for _, k := range []string{"expiring", "forever"} {
+		want := src.shards[0].items[k]
+		if got := dst.shards[0].items[k]; got.Object != want.Object || got.Expiration != want.Expiration {
+			t.Errorf("%s = %+v, want %+v with the original expiration", k, got, want)
+		}
+	}
+}
+
+func TestLoadSkipsExpired(t *testing.T) {
+	src := New[string, string](time.Hour, 0)
+	src.Set("short", "1", 20*time.Millisecond)
+	src.Set("long", "2", DefaultExpiration)
+	snapshot := save(t, src)
+
+	time.Sleep(30 * time.Millisecond)
+
+	dst := New[string, string](time.Hour, 0)
+	loaded, skipped, err := dst.Load(bytes.NewReader(snapshot))
+	if err != nil || loaded != 1 || skipped != 1 {
+		t.Fatalf("Load = %d, %d, %v, want 1 loaded, 1 skipped", loaded, skipped, err)
+	}
+	if _, ok := dst.Get("short"); ok {
+		t.Error("item expired since Save was loaded")
+	}
+}
+
+func TestLoadSkipsOnlyCorruptRecord(t *testing.T) {
+	src := New[string, string](time.Hour, 0)
+	for _, k := range []string{"a", "b", "c"} {
+		src.Set(k, k, DefaultExpiration)
+	}
+	snapshot := save(t, src)
+
+	// Flip a payload byte of the first record, the CRC no longer matches
+	snapshot[recordOffsets(t, snapshot)[0]] ^= 0xff
+
+	dst := New[string, string](time.Hour, 0)
+	loaded, skipped, err := dst.Load(bytes.NewReader(snapshot))
+	if err != nil || loaded != 2 || skipped != 1 {
+		t.Fatalf("Load = %d, %d, %v, want 2 loaded, 1 skipped", loaded, skipped, err)
+	}
+}
+
+func TestLoadStopsAtOversizedLength(t *testing.T) {
+	src := New[string, string](time.Hour, 0)
+	src.Set("a", "a", DefaultExpiration)
+	snapshot := save(t, src)
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], maxRecordSize+1)
+	snapshot = append(snapshot, header[:]...)
+
+	dst := New[string, string](time.Hour, 0)
+	loaded, skipped, err := dst.Load(bytes.NewReader(snapshot))
+	if err == nil || loaded != 1 || skipped != 1 {
+		t.Fatalf("Load = %d, %d, %v, want the record before it and an error", loaded, skipped, err)
+	}
+}
+
+func TestLoadRejectsForeignFile(t *testing.T) {
+	c := New[string, string](time.Hour, 0)
+	if _, _, err := c.Load(bytes.NewReader([]byte("not a snapshot at all"))); err == nil {
+		t.Error("want an error for a stream without the magic header")
+	}
+}
+
+type engine struct {
+	HP int
+}
+
+type unregistered struct{}
+
+// carWithSpecs keeps part of the value behind an interface, like a value stored as any
+type carWithSpecs struct {
+	Name  string
+	Specs any
+}
+
+func TestSaveLoadInterfaceField(t *testing.T) {
+	gob.Register(engine{})
+
+	src := New[string, carWithSpecs](time.Hour, 0)
+	want := carWithSpecs{Name: "BMW M3", Specs: engine{HP: 480}}
+	src.Set("car:1", want, DefaultExpiration)
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := New[string, carWithSpecs](time.Hour, 0)
+	if loaded, _, err := dst.Load(&buf); err != nil || loaded != 1 {
+		t.Fatalf("Load = %d, %v", loaded, err)
+	}
+	if got, _ := dst.Get("car:1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+
+	// Without gob.Register the type can't be encoded
+	src.Set("car:2", carWithSpecs{Specs: unregistered{}}, DefaultExpiration)
+	if err := src.Save(&bytes.Buffer{}); err == nil {
+		t.Error("want an error for an unregistered interface value")
+	}
+}
+
+func TestSaveFileLeavesNoTemp(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cars.cache")
+
+	good := New[string, carWithSpecs](time.Hour, 0)
+	good.Set("car:1", carWithSpecs{Name: "Audi A4"}, DefaultExpiration)
+	if err := good.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing Save removes its temporary file and keeps the previous snapshot
+	bad := New[string, carWithSpecs](time.Hour, 0)
+	bad.Set("car:2", carWithSpecs{Specs: unregistered{}}, DefaultExpiration)
+	if err := bad.SaveFile(path); err == nil {
+		t.Fatal("want an error for an unregistered interface value")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cars.cache" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("dir holds %q, want only cars.cache", names)
+	}
+
+	dst := New[string, carWithSpecs](time.Hour, 0)
+	if loaded, _, err := dst.LoadFile(path); err != nil || loaded != 1 {
+		t.Errorf("LoadFile = %d, %v, want the first snapshot", loaded, err)
+	}
+}
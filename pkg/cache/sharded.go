@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"math/bits"
+	"sync"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
+)
+
+// sharded routes every key to one of its shards. With a single shard it is
+// the plain go-cache behavior, and no hashing is done.
+type sharded[K comparable, V any] struct {
+	shards  []*cache[K, V]
+	seed    maphash.Seed
+	mask    uint64
+	janitor *janitor
+	stats   *statsRecorder[K]
+	flight  singleflight.Group[K, V] // dedupes GetOrLoad loads per key
+
+	refreshing sync.Map // keys with a stale refresh in flight
+}
+
+func newSharded[K comparable, V any](de time.Duration, o options[K, V]) *sharded[K, V] {
+	n := 1 << bits.Len(uint(o.shards-1)) // next power of two, so a mask picks the shard
+
+	// Every shard needs a share of the capacity, a shard with a zero limit would be unbounded
+	for n > 1 && ((o.maxEntries > 0 && o.maxEntries < n) || (o.maxBytes > 0 && o.maxBytes < int64(n))) {
+		n >>= 1
+	}
+
+	s := &sharded[K, V]{
+		shards: make([]*cache[K, V], n),
+		seed:   maphash.MakeSeed(),
+		mask:   uint64(n - 1),
+		stats:  newStatsRecorder(o.group),
+	}
+
+	for i := range s.shards {
+		c := newCache(de, make(map[K]Item[V]))
+		c.stats = s.stats
+		c.stale = o.stale
+		c.tagger = o.tagger
+		if o.maxEntries > 0 || o.maxBytes > 0 {
+			c.lru = newLRU[K, V]()
+			c.lru.maxEntries = share(o.maxEntries, n, i)
+			c.lru.maxBytes = share(o.maxBytes, int64(n), int64(i))
+			c.lru.sizeOf = o.sizeOf
+		}
+		s.shards[i] = c
+	}
+
+	return s
+}
+
+// share is the part of total that shard i of n gets. The remainder goes to the first shards,
+// so the shares add up to exactly total.
+func share[T int | int64](total, n, i T) T {
+	if i < total%n {
+		return total/n + 1
+	}
+	return total / n
+}
+
+func (s *sharded[K, V]) shard(k K) *cache[K, V] {
+	if s.mask == 0 {
+		return s.shards[0]
+	}
+	return s.shards[maphash.Comparable(s.seed, k)&s.mask]
+}
+
+// Add an item to the cache, replacing any existing item. If the duration is 0
+// (DefaultExpiration), the cache's default expiration time is used.
+func (s *sharded[K, V]) Set(k K, x V, d time.Duration) {
+	s.shard(k).Set(k, x, d)
+}
+
+// Add an item to the cache only if an item doesn't already exist for the given
+// key, or if the existing item has expired. Returns an error otherwise.
+func (s *sharded[K, V]) Add(k K, x V, d time.Duration) error {
+	return s.shard(k).Add(k, x, d)
+}
+
+// Set a new value for the cache key only if it already exists, and the existing
+// item hasn't expired. Returns an error otherwise.
+func (s *sharded[K, V]) Replace(k K, x V, d time.Duration) error {
+	return s.shard(k).Replace(k, x, d)
+}
+
+// Get an item from the cache. Returns the item or the zero value of V, and a bool
+// indicating whether the key was found.
+func (s *sharded[K, V]) Get(k K) (V, bool) {
+	return s.shard(k).Get(k)
+}
+
+// Delete an item from the cache. Does nothing if the key is not in the cache.
+func (s *sharded[K, V]) Delete(k K) {
+	s.shard(k).Delete(k)
+}
+
+// Delete all expired items from the cache. Shards are swept one by one,
+// so only one shard at a time is write locked.
+func (s *sharded[K, V]) DeleteExpired() {
+	for _, c := range s.shards {
+		c.DeleteExpired()
+	}
+}
+
+// Sets an (optional) function that is called with the key and value when an
+// item is evicted from the cache. (Including when it is deleted manually, but
+// not when it is overwritten.) Set to nil to disable.
+func (s *sharded[K, V]) OnEvicted(f func(K, V)) {
+	for _, c := range s.shards {
+		c.OnEvicted(f)
+	}
+}
+
+// Returns the number of items in the cache. This may include items that have
+// expired, but have not yet been cleaned up.
+func (s *sharded[K, V]) ItemCount() int {
+	n := 0
+	for _, c := range s.shards {
+		n += c.ItemCount()
+	}
+	return n
+}
+
+// Delete all items from the cache.
+func (s *sharded[K, V]) Flush() {
+	for _, c := range s.shards {
+		c.Flush()
+	}
+}
+
+// Returns the number of items evicted to keep the cache within its capacity.
+// Expired and manually deleted items are not counted.
+func (s *sharded[K, V]) Evictions() uint64 {
+	var n uint64
+	for _, c := range s.shards {
+		n += c.Evictions()
+	}
+	return n
+}
+
+// Drops every item tagged with tag and returns how many were dropped.
+// OnEvicted is called for them like for deleted items.
+func (s *sharded[K, V]) InvalidateTag(tag string) int {
+	n := 0
+	for _, c := range s.shards {
+		n += c.InvalidateTag(tag)
+	}
+	return n
+}
+
+// StopJanitor stops the background cleanup and waits for it to exit, or for ctx to be done, e.g. on shutdown.
+// It is idempotent and a no-op without a cleanup interval. Afterwards expired items
+// are still hidden from Get, but are removed only by an explicit DeleteExpired.
+func (s *sharded[K, V]) StopJanitor(ctx context.Context) error {
+	if s.janitor == nil {
+		return nil
+	}
+	return s.janitor.Stop(ctx)
+}
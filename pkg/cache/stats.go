@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Stats are the counters of one group of keys, see WithStatsGroups.
+type Stats struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	StaleHits   uint64 `json:"stale_hits"` // served by GetOrLoad while refreshing
+	Sets        uint64 `json:"sets"`
+	Expirations uint64 `json:"expirations"` // removed by DeleteExpired / the janitor
+	Evictions   uint64 `json:"evictions"`   // removed to stay within capacity
+	Invalidated uint64 `json:"invalidated"` // removed by InvalidateTag
+	Items       int    `json:"items"`       // may include expired items not cleaned up yet
+}
+
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	staleHits   atomic.Uint64
+	sets        atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+	invalidated atomic.Uint64
+}
+
+// statsRecorder counts cache events per group of keys. It is shared by all shards.
+type statsRecorder[K comparable] struct {
+	group  func(K) string
+	groups sync.Map // string -> *counters
+}
+
+func newStatsRecorder[K comparable](group func(K) string) *statsRecorder[K] {
+	if group == nil {
+		group = func(K) string { return "" }
+	}
+	return &statsRecorder[K]{group: group}
+}
+
+func (r *statsRecorder[K]) of(k K) *counters {
+	g := r.group(k)
+	if c, ok := r.groups.Load(g); ok {
+		return c.(*counters)
+	}
+	c, _ := r.groups.LoadOrStore(g, &counters{})
+	return c.(*counters)
+}
+
+// Stats returns the counters for every group of keys seen so far, plus the current item count.
+// Without WithStatsGroups all keys are counted under "".
+func (s *sharded[K, V]) Stats() map[string]Stats {
+	items := make(map[string]int)
+	for _, c := range s.shards {
+		c.mu.RLock()
+		for k := range c.items {
+			items[s.stats.group(k)]++
+		}
+		c.mu.RUnlock()
+	}
+
+	stats := make(map[string]Stats)
+	s.stats.groups.Range(func(g, v any) bool {
+		c := v.(*counters)
+		stats[g.(string)] = Stats{
+			Hits:        c.hits.Load(),
+			Misses:      c.misses.Load(),
+			StaleHits:   c.staleHits.Load(),
+			Sets:        c.sets.Load(),
+			Expirations: c.expirations.Load(),
+			Evictions:   c.evictions.Load(),
+			Invalidated: c.invalidated.Load(),
+			Items:       items[g.(string)],
+		}
+		return true
+	})
+
+	// Items restored from a snapshot may not have any counters yet
+	for g, n := range items {
+		if _, ok := stats[g]; !ok {
+			stats[g] = Stats{Items: n}
+		}
+	}
+
+	return stats
+}
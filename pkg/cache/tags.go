@@ -0,0 +1,61 @@
+package cache
+
+// tagsOf returns the tags of a new item, nil without WithTags.
+func (c *cache[K, V]) tagsOf(k K, x V) []string {
+	if c.tagger == nil {
+		return nil
+	}
+	return c.tagger(k, x)
+}
+
+// store puts an item into the map and keeps the tag index in sync with it.
+// It must be called under the write lock.
+func (c *cache[K, V]) store(k K, item Item[V]) {
+	if old, found := c.items[k]; found {
+		c.untag(k, old.Tags)
+	}
+	c.items[k] = item
+
+	if len(item.Tags) == 0 {
+		return
+	}
+	if c.tagged == nil {
+		c.tagged = make(map[string]map[K]struct{})
+	}
+	for _, tag := range item.Tags {
+		keys, ok := c.tagged[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tagged[tag] = keys
+		}
+		keys[k] = struct{}{}
+	}
+}
+
+func (c *cache[K, V]) untag(k K, tags []string) {
+	for _, tag := range tags {
+		keys := c.tagged[tag]
+		delete(keys, k)
+		if len(keys) == 0 {
+			delete(c.tagged, tag)
+		}
+	}
+}
+
+// InvalidateTag drops every item of the shard tagged with tag.
+func (c *cache[K, V]) InvalidateTag(tag string) int {
+	var evictedItems []keyAndValue[K, V]
+	c.mu.Lock()
+	keys := c.tagged[tag]
+	n := len(keys)
+	for k := range keys {
+		v, evicted := c.delete(k)
+		c.stats.of(k).invalidated.Add(1)
+		if evicted {
+			evictedItems = append(evictedItems, keyAndValue[K, V]{k, v})
+		}
+	}
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+	return n
+}
@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// State of the circuit breaker
+type State int
+
+const (
+	StateClosed   State = iota // upstream is healthy, requests go through
+	StateOpen                  // upstream is down, requests fail fast with ErrCircuitOpen
+	StateHalfOpen              // cooldown passed, a single probe request is let through
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker opens after threshold consecutive failed requests and stays open for cooldown.
+// After the cooldown only one probe is allowed: success closes it, failure opens it again.
+type breaker struct {
+	mu        sync.Mutex
+	state     State
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool
+	onChange  func(from, to State)
+}
+
+func newBreaker(threshold int, cooldown time.Duration, onChange func(from, to State)) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+	}
+}
+
+// allow reports whether a request may be sent upstream.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+
+	switch b.state {
+	case StateClosed:
+		b.mu.Unlock()
+		return true
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			b.mu.Unlock()
+			return false
+		}
+		from := b.state
+		b.state = StateHalfOpen
+		b.probing = true
+		b.mu.Unlock()
+		b.notify(from, StateHalfOpen)
+		return true
+	default: // StateHalfOpen
+		defer b.mu.Unlock()
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	from := b.state
+	b.failures = 0
+	b.probing = false
+	b.state = StateClosed
+	b.mu.Unlock()
+
+	b.notify(from, StateClosed)
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	from := b.state
+	b.probing = false
+	b.failures++
+
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.state = StateOpen
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release frees the probe slot when a request ended without telling anything about upstream health,
+// e.g. the caller cancelled it.
+func (b *breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// notify calls the onChange hook, outside of the lock
+func (b *breaker) notify(from, to State) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
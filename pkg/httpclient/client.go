@@ -2,53 +2,277 @@ package httpclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
+)
+
+var (
+	// ErrNotFound matches errors of requests answered with 404.
+	ErrNotFound = errors.New("resource not found")
+
+	// ErrUnavailable matches errors caused by upstream being down:
+	// network failures, 5xx and 429 statuses, and an open circuit breaker.
+	ErrUnavailable = errors.New("upstream unavailable")
+
+	// ErrCircuitOpen is returned without calling upstream while the circuit breaker is open.
+	ErrCircuitOpen = fmt.Errorf("circuit breaker is open: %w", ErrUnavailable)
 )
 
+// StatusError is returned when upstream answers with a non 200 status code.
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // parsed Retry-After header, 0 if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// Is lets errors.Is match a StatusError with ErrNotFound or ErrUnavailable.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == http.StatusNotFound
+	case ErrUnavailable:
+		return retryable(e.Code)
+	}
+	return false
+}
+
 type Client struct {
 	host    string
 	timeout time.Duration
 	client  http.Client
+	retry   retryPolicy
+	breaker *breaker        // nil when disabled
+	cache   *validatorCache // nil when disabled
+
+	coalesce bool
+	flight   singleflight.Group[string, []byte]
 }
 
-func New(host string, timeout time.Duration) *Client {
-	return &Client{
+type Option func(*Client)
+
+// WithRetry retries failed GETs (network errors, 429 and 5xx) up to maxRetries times
+// with jittered exponential backoff between baseDelay and maxDelay.
+// A Retry-After header from upstream overrides the computed delay, capped at maxDelay.
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retryPolicy{
+			maxRetries: maxRetries,
+			baseDelay:  baseDelay,
+			maxDelay:   maxDelay,
+		}
+	}
+}
+
+// WithCircuitBreaker makes the client fail fast with ErrCircuitOpen after threshold consecutive failed requests,
+// for the cooldown duration. onChange is optional and is called on every state transition.
+func WithCircuitBreaker(threshold int, cooldown time.Duration, onChange func(from, to State)) Option {
+	return func(c *Client) {
+		if threshold > 0 {
+			c.breaker = newBreaker(threshold, cooldown, onChange)
+		}
+	}
+}
+
+// WithConditionalRequests remembers ETag / Last-Modified of responses and revalidates them
+// with If-None-Match / If-Modified-Since, a 304 answer reuses the stored body.
+// At most maxEntries URLs are remembered, 0 means DefaultValidatorEntries.
+func WithConditionalRequests(maxEntries int) Option {
+	return func(c *Client) {
+		c.cache = newValidatorCache(maxEntries)
+	}
+}
+
+// WithRequestCoalescing makes concurrent requests for the same URL share one upstream call.
+// Every caller is still cancelled by its own context, the shared call keeps the deadline of the first one.
+func WithRequestCoalescing() Option {
+	return func(c *Client) {
+		c.coalesce = true
+	}
+}
+
+func New(host string, timeout time.Duration, opts ...Option) *Client {
+	c := &Client{
 		host:   host,
 		client: http.Client{Timeout: timeout},
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-func (c *Client) DoRequest(ctx context.Context, path string) (data []byte, err error) {
-	const op = "httpclient.DoRequest"
+// State reports the circuit breaker state, it is always closed if the breaker is disabled.
+func (c *Client) State() State {
+	if c.breaker == nil {
+		return StateClosed
+	}
+	return c.breaker.State()
+}
 
+// DoRequest GETs the path relative to the host and returns the response body.
+// With conditional requests or coalescing enabled the body can be shared between callers, so it must not be modified.
+func (c *Client) DoRequest(ctx context.Context, path string) (data []byte, err error) {
 	URL, err := url.JoinPath(c.host, path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid url: %w", err)
 	}
 
+	if c.coalesce {
+		body, err, _ := c.flight.Do(ctx, URL, func(shared context.Context) ([]byte, error) {
+			// The shared context outlives the callers and has no deadline,
+			// keep the first caller's so retries can't go on after everyone would have given up
+			if deadline, ok := ctx.Deadline(); ok {
+				var cancel context.CancelFunc
+				shared, cancel = context.WithDeadline(shared, deadline)
+				defer cancel()
+			}
+			return c.doWithRetry(shared, URL)
+		})
+		return body, err
+	}
+
+	return c.doWithRetry(ctx, URL)
+}
+
+// doWithRetry makes attempts until success, a non retryable error or the retry budget is spent.
+func (c *Client) doWithRetry(ctx context.Context, URL string) ([]byte, error) {
+	if c.breaker != nil && !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.do(ctx, URL)
+		if err == nil {
+			c.recordSuccess()
+			return body, nil
+		}
+
+		// Caller gave up, it says nothing about upstream health
+		if ctx.Err() != nil {
+			c.recordCancel()
+			return nil, err
+		}
+
+		var statusErr *StatusError
+		isStatus := errors.As(err, &statusErr)
+
+		// Client errors (e.g. 404) mean upstream is alive and retrying won't help
+		if isStatus && !retryable(statusErr.Code) {
+			c.recordSuccess()
+			return nil, err
+		}
+
+		if attempt >= c.retry.maxRetries {
+			c.recordFailure()
+			return nil, err
+		}
+
+		wait := c.retry.backoff(attempt)
+		if isStatus && statusErr.RetryAfter > 0 {
+			// Don't let upstream park us for an hour
+			wait = min(statusErr.RetryAfter, c.retry.maxDelay)
+		}
+
+		// Don't sleep if the caller's deadline comes first
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			c.recordFailure()
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.recordCancel()
+			return nil, fmt.Errorf("retry cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// CloseIdleConnections closes kept-alive connections to upstream, e.g. on shutdown.
+func (c *Client) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+// CacheStats reports hits and misses of conditional requests, zero if they are disabled.
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.stats()
+}
+
+// do makes a single attempt
+func (c *Client) do(ctx context.Context, URL string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("can't create request: %w", err)
 	}
 
+	if c.cache != nil {
+		c.cache.setValidators(URL, req)
+	}
+
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("can't do request: %w", err)
+		return nil, fmt.Errorf("can't do request: %w: %w", ErrUnavailable, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusNotModified && c.cache != nil {
+		if body, ok := c.cache.notModified(URL); ok {
+			return body, nil
+		}
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Drain, so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil, &StatusError{
+			Code:       resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("can't read body: %w", err)
+		return nil, fmt.Errorf("can't read body: %w: %w", ErrUnavailable, err)
+	}
+
+	if c.cache != nil {
+		c.cache.store(URL, resp.Header, body)
 	}
 
 	return body, nil
 }
+
+func (c *Client) recordSuccess() {
+	if c.breaker != nil {
+		c.breaker.success()
+	}
+}
+
+func (c *Client) recordFailure() {
+	if c.breaker != nil {
+		c.breaker.failure()
+	}
+}
+
+func (c *Client) recordCancel() {
+	if c.breaker != nil {
+		c.breaker.release()
+	}
+}
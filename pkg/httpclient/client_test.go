@@ -0,0 +1,320 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedServer calls respond with the 1-based number of every request and counts them
+func scriptedServer(t *testing.T, respond func(n int, w http.ResponseWriter, r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(int(hits.Add(1)), w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &hits
+}
+
+// statuses answers the requests with the given codes in order, the last one repeats
+func statuses(codes ...int) func(int, http.ResponseWriter, *http.Request) {
+	return func(n int, w http.ResponseWriter, r *http.Request) {
+		code := codes[min(n, len(codes))-1]
+		if code == http.StatusOK {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	srv, hits := scriptedServer(t, statuses(503, 429, 200))
+	c := New(srv.URL, time.Second, WithRetry(2, time.Millisecond, 5*time.Millisecond))
+
+	body, err := c.DoRequest(context.Background(), "/models")
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("DoRequest = %q, %v", body, err)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestRetryBudgetSpent(t *testing.T) {
+	srv, hits := scriptedServer(t, statuses(503))
+	c := New(srv.URL, time.Second, WithRetry(2, time.Millisecond, 5*time.Millisecond))
+
+	_, err := c.DoRequest(context.Background(), "/models")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != 503 || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want an unavailable 503 StatusError", err)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 1 + 2 retries", n)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	srv, hits := scriptedServer(t, statuses(404))
+	c := New(srv.URL, time.Second, WithRetry(3, time.Millisecond, 5*time.Millisecond))
+
+	if _, err := c.DoRequest(context.Background(), "/models/99"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestRetryAfterCappedAtMaxDelay(t *testing.T) {
+	srv, hits := scriptedServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	c := New(srv.URL, time.Second, WithRetry(1, time.Millisecond, 20*time.Millisecond))
+
+	start := time.Now()
+	body, err := c.DoRequest(context.Background(), "/models")
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("DoRequest = %q, %v", body, err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took %s, Retry-After should be capped at the 20ms max delay", took)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+}
+
+func TestNoRetryPastDeadline(t *testing.T) {
+	srv, hits := scriptedServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c := New(srv.URL, time.Second, WithRetry(3, time.Millisecond, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.DoRequest(ctx, "/models")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != 503 {
+		t.Errorf("err = %v, want the 503 right away", err)
+	}
+	if took := time.Since(start); took > 250*time.Millisecond {
+		t.Errorf("took %s, a 10s wait past the deadline shouldn't be slept", took)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	p := retryPolicy{baseDelay: 10 * time.Millisecond, maxDelay: 100 * time.Millisecond}
+
+	for attempt := range 70 { // past the point where the shift overflows
+		limit := min(p.maxDelay, p.baseDelay<<attempt)
+		if limit <= 0 {
+			limit = p.maxDelay
+		}
+		for range 50 {
+			if d := p.backoff(attempt); d < 0 || d > limit {
+				t.Fatalf("backoff(%d) = %s, want within [0, %s]", attempt, d, limit)
+			}
+		}
+	}
+}
+
+// transitions records the breaker state changes
+type transitions struct {
+	mu   sync.Mutex
+	seen []State
+}
+
+func (tr *transitions) record(from, to State) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.seen) == 0 {
+		tr.seen = append(tr.seen, from)
+	}
+	tr.seen = append(tr.seen, to)
+}
+
+func (tr *transitions) get() []State {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return slices.Clone(tr.seen)
+}
+
+const testCooldown = 30 * time.Millisecond
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	var healthy atomic.Bool
+	probe := make(chan struct{})
+	srv, hits := scriptedServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		<-probe
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	var tr transitions
+	c := New(srv.URL, time.Second, WithCircuitBreaker(2, testCooldown, tr.record))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.DoRequest(ctx, "/models"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("breaker opened before the threshold")
+		}
+	}
+	if s := c.State(); s != StateOpen {
+		t.Fatalf("state after 2 failures = %s, want open", s)
+	}
+
+	// Open: fail fast without calling upstream
+	if _, err := c.DoRequest(ctx, "/models"); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream hits = %d, want 2, the open breaker shouldn't call it", n)
+	}
+
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	if s := c.State(); s != StateHalfOpen {
+		t.Fatalf("state after the cooldown = %s, want half-open", s)
+	}
+
+	// One probe goes through, everybody else fails fast while it's in flight
+	healthy.Store(true)
+	probeDone := make(chan error, 1)
+	go func() {
+		_, err := c.DoRequest(ctx, "/models")
+		probeDone <- err
+	}()
+	waitHits(t, hits, 3)
+
+	if _, err := c.DoRequest(ctx, "/models"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second request during the probe err = %v, want ErrCircuitOpen", err)
+	}
+
+	close(probe)
+	if err := <-probeDone; err != nil {
+		t.Fatalf("probe err = %v", err)
+	}
+	if s := c.State(); s != StateClosed {
+		t.Errorf("state after a good probe = %s, want closed", s)
+	}
+
+	want := []State{StateClosed, StateOpen, StateHalfOpen, StateClosed}
+	if got := tr.get(); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	srv, _ := scriptedServer(t, statuses(503))
+
+	var tr transitions
+	c := New(srv.URL, time.Second, WithCircuitBreaker(1, testCooldown, tr.record))
+	ctx := context.Background()
+
+	_, _ = c.DoRequest(ctx, "/models")
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	_, _ = c.DoRequest(ctx, "/models")
+
+	if s := c.State(); s != StateOpen {
+		t.Errorf("state after a failed probe = %s, want open", s)
+	}
+	want := []State{StateClosed, StateOpen, StateHalfOpen, StateOpen}
+	if got := tr.get(); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestBreakerCancelledProbeFreesSlot(t *testing.T) {
+	var healthy atomic.Bool
+	srv, hits := scriptedServer(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if n == 2 {
+			<-r.Context().Done() // the cancelled probe
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	c := New(srv.URL, time.Second, WithCircuitBreaker(1, testCooldown, nil))
+
+	_, _ = c.DoRequest(context.Background(), "/models")
+	time.Sleep(testCooldown + 10*time.Millisecond)
+	healthy.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	probeDone := make(chan error, 1)
+	go func() {
+		_, err := c.DoRequest(ctx, "/models")
+		probeDone <- err
+	}()
+	waitHits(t, hits, 2)
+	cancel()
+	if err := <-probeDone; err == nil {
+		t.Fatal("cancelled probe returned no error")
+	}
+
+	// The cancellation says nothing about upstream, the next request may probe
+	body, err := c.DoRequest(context.Background(), "/models")
+	if err != nil || string(body) != "ok" {
+		t.Errorf("request after a cancelled probe = %q, %v, want a new probe", body, err)
+	}
+	if s := c.State(); s != StateClosed {
+		t.Errorf("state = %s, want closed", s)
+	}
+}
+
+func TestBreakerClientErrorsCountAsSuccess(t *testing.T) {
+	srv, _ := scriptedServer(t, statuses(503, 404, 503, 404, 503))
+	c := New(srv.URL, time.Second, WithCircuitBreaker(2, time.Minute, nil))
+
+	for range 5 {
+		_, _ = c.DoRequest(context.Background(), "/models")
+	}
+
+	// A 404 means upstream is alive, so no two failures in a row
+	if s := c.State(); s != StateClosed {
+		t.Errorf("state = %s, want closed", s)
+	}
+}
+
+// waitHits waits until upstream has seen n requests
+func waitHits(t *testing.T, hits *atomic.Int32, n int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hits.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("upstream saw %d requests, want %d", hits.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingServer signals entered on every request and answers "ok" once release is closed
+func blockingServer(t *testing.T, entered chan<- struct{}, release <-chan struct{}) (string, *atomic.Int32) {
+	t.Helper()
+
+	srv, hits := scriptedServer(t, func(_ int, w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		select {
+		case <-release:
+			_, _ = w.Write([]byte("ok"))
+		case <-r.Context().Done():
+		}
+	})
+
+	return srv.URL, hits
+}
+
+// waitWaiters waits until n callers have joined the shared request for URL
+func waitWaiters(t *testing.T, c *Client, URL string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for c.flight.Waiters(URL) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers joined, want %d", c.flight.Waiters(URL), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalescedRequestsHitUpstreamOnce(t *testing.T) {
+	const callers = 50
+
+	entered := make(chan struct{}, callers)
+	release := make(chan struct{})
+	host, hits := blockingServer(t, entered, release)
+	c := New(host, 5*time.Second, WithRequestCoalescing())
+
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Int32
+	)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := c.DoRequest(context.Background(), "/models")
+			if err != nil || string(body) != "ok" {
+				failed.Add(1)
+			}
+		}()
+	}
+
+	<-entered
+	waitWaiters(t, c, host+"/models", callers)
+	close(release)
+	wg.Wait()
+
+	if n := failed.Load(); n > 0 {
+		t.Errorf("%d of %d callers didn't get the body", n, callers)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hits = %d, want 1", n)
+	}
+}
+
+func TestCancelledCallerDoesntFailOthers(t *testing.T) {
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	host, hits := blockingServer(t, entered, release)
+	c := New(host, 5*time.Second, WithRequestCoalescing())
+	URL := host + "/models"
+
+	impatient, cancel := context.WithCancel(context.Background())
+	impatientErr := make(chan error, 1)
+	go func() {
+		_, err := c.DoRequest(impatient, "/models")
+		impatientErr <- err
+	}()
+	<-entered
+
+	type result struct {
+		body []byte
+		err  error
+	}
+	patient := make(chan result, 1)
+	go func() {
+		body, err := c.DoRequest(context.Background(), "/models")
+		patient <- result{body, err}
+	}()
+	waitWaiters(t, c, URL, 2)
+
+	cancel()
+	if err := <-impatientErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller err = %v, want context.Canceled", err)
+	}
+	if n := c.flight.Waiters(URL); n != 1 {
+		t.Errorf("%d waiters after one left, want 1", n)
+	}
+
+	close(release)
+	res := <-patient
+	if res.err != nil || string(res.body) != "ok" {
+		t.Errorf("remaining caller got %q, %v, want \"ok\"", res.body, res.err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hits = %d, want 1", n)
+	}
+}
@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultValidatorEntries is the number of URLs WithConditionalRequests remembers when no limit is given.
+const DefaultValidatorEntries = 1000
+
+// validatorCache remembers ETag / Last-Modified validators and bodies of earlier responses per URL,
+// so repeated requests can be answered by upstream with a bodyless 304 Not Modified.
+// It holds at most maxEntries URLs, the least recently requested one is dropped first.
+type validatorCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element // of *validatorEntry
+	lru        *list.List               // front is the most recently requested
+	maxEntries int
+
+	hits   atomic.Uint64 // 304 answered, stored body reused
+	misses atomic.Uint64 // full body downloaded
+}
+
+type validatorEntry struct {
+	URL          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// CacheStats shows how effective conditional requests are.
+type CacheStats struct {
+	Hits    uint64 // upstream answered 304 and the stored body was reused
+	Misses  uint64 // upstream sent the full body
+	Entries int    // URLs with stored validators
+}
+
+func newValidatorCache(maxEntries int) *validatorCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultValidatorEntries
+	}
+
+	return &validatorCache{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+	}
+}
+
+// setValidators adds conditional headers to the request, if validators for its URL are known.
+func (vc *validatorCache) setValidators(URL string, req *http.Request) {
+	vc.mu.Lock()
+	el, ok := vc.entries[URL]
+	if ok {
+		vc.lru.MoveToFront(el)
+	}
+	vc.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// Entries are replaced, never changed in place, so reading it unlocked is fine
+	entry := el.Value.(*validatorEntry)
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+}
+
+// notModified returns the stored body for a 304 response.
+func (vc *validatorCache) notModified(URL string) ([]byte, bool) {
+	vc.mu.Lock()
+	el, ok := vc.entries[URL]
+	vc.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	vc.hits.Add(1)
+	return el.Value.(*validatorEntry).body, true
+}
+
+// store remembers validators and body of a 200 response. Responses without validators are not stored.
+func (vc *validatorCache) store(URL string, header http.Header, body []byte) {
+	vc.misses.Add(1)
+
+	entry := &validatorEntry{
+		URL:          URL,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		body:         body,
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if el, ok := vc.entries[URL]; ok {
+		vc.lru.Remove(el)
+		delete(vc.entries, URL)
+	}
+	if entry.etag == "" && entry.lastModified == "" {
+		return
+	}
+
+	vc.entries[URL] = vc.lru.PushFront(entry)
+	for vc.lru.Len() > vc.maxEntries {
+		oldest := vc.lru.Back()
+		vc.lru.Remove(oldest)
+		delete(vc.entries, oldest.Value.(*validatorEntry).URL)
+	}
+}
+
+func (vc *validatorCache) stats() CacheStats {
+	vc.mu.Lock()
+	n := len(vc.entries)
+	vc.mu.Unlock()
+
+	return CacheStats{
+		Hits:    vc.hits.Load(),
+		Misses:  vc.misses.Load(),
+		Entries: n,
+	}
+}
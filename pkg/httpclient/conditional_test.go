@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testLastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// versionedServer serves "<path> <version>" with an ETag and Last-Modified,
+// answers 304 to a matching If-None-Match and remembers which paths were asked conditionally.
+type versionedServer struct {
+	version atomic.Value // string
+
+	mu          sync.Mutex
+	conditional map[string]bool
+}
+
+func newVersionedServer(t *testing.T) (*versionedServer, string) {
+	vs := &versionedServer{conditional: make(map[string]bool)}
+	vs.version.Store("v1")
+
+	srv, _ := scriptedServer(t, func(_ int, w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + "-" + vs.version.Load().(string) + `"`
+
+		vs.mu.Lock()
+		vs.conditional[r.URL.Path] = r.Header.Get("If-None-Match") != "" &&
+			r.Header.Get("If-Modified-Since") == testLastModified
+		vs.mu.Unlock()
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", testLastModified)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path + " " + vs.version.Load().(string)))
+	})
+
+	return vs, srv.URL
+}
+
+func (vs *versionedServer) wasConditional(path string) bool {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return vs.conditional[path]
+}
+
+func TestConditionalReusesBodyOn304(t *testing.T) {
+	vs, host := newVersionedServer(t)
+	c := New(host, time.Second, WithConditionalRequests(0))
+	ctx := context.Background()
+
+	if body, err := c.DoRequest(ctx, "/models"); err != nil || string(body) != "/models v1" {
+		t.Fatalf("first request = %q, %v", body, err)
+	}
+	if vs.wasConditional("/models") {
+		t.Error("first request sent validators it couldn't know")
+	}
+
+	body, err := c.DoRequest(ctx, "/models")
+	if err != nil || string(body) != "/models v1" {
+		t.Fatalf("revalidated request = %q, %v, want the stored body", body, err)
+	}
+	if !vs.wasConditional("/models") {
+		t.Error("second request didn't send If-None-Match and If-Modified-Since")
+	}
+	if st := c.CacheStats(); st.Hits != 1 || st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("stats = %+v, want 1 hit, 1 miss, 1 entry", st)
+	}
+
+	// Changed upstream: full body again, and its new ETag is used next time
+	vs.version.Store("v2")
+	if body, _ := c.DoRequest(ctx, "/models"); string(body) != "/models v2" {
+		t.Errorf("after a change = %q, want the new body", body)
+	}
+	if body, _ := c.DoRequest(ctx, "/models"); string(body) != "/models v2" {
+		t.Errorf("revalidated after a change = %q, want the new body", body)
+	}
+	if st := c.CacheStats(); st.Hits != 2 || st.Misses != 2 {
+		t.Errorf("stats = %+v, want 2 hits, 2 misses", st)
+	}
+}
+
+func TestConditionalSkipsResponsesWithoutValidators(t *testing.T) {
+	var conditional atomic.Bool
+	srv, _ := scriptedServer(t, func(_ int, w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			conditional.Store(true)
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	c := New(srv.URL, time.Second, WithConditionalRequests(0))
+
+	for range 2 {
+		if _, err := c.DoRequest(context.Background(), "/models"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if conditional.Load() {
+		t.Error("sent validators upstream never gave")
+	}
+	if st := c.CacheStats(); st.Entries != 0 || st.Misses != 2 {
+		t.Errorf("stats = %+v, want no entries and 2 misses", st)
+	}
+}
+
+func TestConditionalDropsLeastRecentlyRequested(t *testing.T) {
+	vs, host := newVersionedServer(t)
+	c := New(host, time.Second, WithConditionalRequests(2))
+	ctx := context.Background()
+
+	for _, path := range []string{"/a", "/b", "/a", "/c"} { // /a is requested again, so /b is the oldest
+		if _, err := c.DoRequest(ctx, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := c.CacheStats(); st.Entries != 2 {
+		t.Errorf("entries = %d, want the limit of 2", st.Entries)
+	}
+
+	if _, err := c.DoRequest(ctx, "/a"); err != nil {
+		t.Fatal(err)
+	}
+	if !vs.wasConditional("/a") {
+		t.Error("/a lost its validators, but it was used more recently than /b")
+	}
+
+	if _, err := c.DoRequest(ctx, "/b"); err != nil {
+		t.Fatal(err)
+	}
+	if vs.wasConditional("/b") {
+		t.Error("/b kept its validators, but it should be dropped as the least recently requested")
+	}
+}
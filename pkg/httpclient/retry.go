@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryPolicy describes how failed idempotent GETs are retried.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// backoff returns a full jitter exponential delay for the given attempt (starting from 0):
+// random value in [0, min(maxDelay, baseDelay * 2^attempt)].
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay << attempt
+	if d <= 0 || d > p.maxDelay { // <= 0 on overflow
+		d = p.maxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// retryable reports whether the status code is worth another attempt.
+func retryable(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter supports both forms of the header: delay in seconds and HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Lifecycle starts application components in the order they were appended
+// and stops them in reverse order, so a component is stopped before the ones it depends on.
+
+// Hook is one component. Start and Stop are optional.
+// Stop must return once ctx is done, even if the component hasn't stopped yet.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// StopError reports a component that failed to stop, or didn't stop in time.
+type StopError struct {
+	Name string
+	Err  error
+}
+
+func (e *StopError) Error() string {
+	return fmt.Sprintf("stop %s: %v", e.Name, e.Err)
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+type Lifecycle struct {
+	log     *slog.Logger
+	mu      sync.Mutex
+	hooks   []Hook
+	started int // hooks[:started] were started and have to be stopped
+	failed  chan error
+}
+
+func New(log *slog.Logger) *Lifecycle {
+	return &Lifecycle{
+		log:    log,
+		failed: make(chan error, 1),
+	}
+}
+
+// Append registers a component. Components appended after Start are not started.
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	l.hooks = append(l.hooks, h)
+	l.mu.Unlock()
+}
+
+// Go registers a component that runs in its own goroutine until its context is cancelled.
+// Stop cancels it and waits for run to return. If run returns an error by itself,
+// the error is reported through Failed.
+func (l *Lifecycle) Go(name string, run func(ctx context.Context) error) {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+
+	l.Append(Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			// Not the Start context: it may be a startup timeout, while run lives until Stop
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+
+			go func() {
+				defer close(done)
+				if err := run(ctx); err != nil && ctx.Err() == nil {
+					l.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Fail reports a fatal error of a running component. Only the first error is kept.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Failed receives the first error reported by Fail, so the app can shut down.
+func (l *Lifecycle) Failed() <-chan error {
+	return l.failed
+}
+
+// Start starts the components in order. If one fails, the already started ones
+// are stopped in reverse order and the start error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.started < len(l.hooks) {
+		h := l.hooks[l.started]
+
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", h.Name, err)
+				return errors.Join(err, l.stop(ctx))
+			}
+		}
+
+		l.log.Debug("component started", slog.String("component", h.Name))
+		l.started++
+	}
+
+	return nil
+}
+
+// Stop stops the started components in reverse order within ctx.
+// Every component gets its Stop call, even after an earlier one failed or ctx expired.
+// The returned error joins a StopError for each component that failed to stop.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.Stop == nil {
+			continue
+		}
+
+		if err := h.Stop(ctx); err != nil {
+			l.log.Error("component failed to stop",
+				slog.String("component", h.Name),
+				slog.Any("error", err),
+			)
+			errs = append(errs, &StopError{Name: h.Name, Err: err})
+			continue
+		}
+
+		l.log.Debug("component stopped", slog.String("component", h.Name))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/pkg/cache"
+	"gitea.kood.tech/ivanandreev/viewer/pkg/lifecycle"
+)
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// settledGoroutines waits for goroutines that are on their way out, up to a second
+func settledGoroutines(want int) int {
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); n > want && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
+
+func TestStopLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	lc := lifecycle.New(discard())
+
+	for _, name := range []string{"refresher", "watcher", "ticker"} {
+		lc.Go(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+	}
+
+	c := cache.New[string, int](time.Minute, 10*time.Millisecond)
+	lc.Append(lifecycle.Hook{
+		Name: "cache janitor",
+		Stop: c.StopJanitor,
+	})
+
+	if err := lc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("components didn't start any goroutines")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lc.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := settledGoroutines(before); after > before {
+		t.Errorf("%d goroutines before start, %d after stop", before, after)
+	}
+}
+
+func TestStopInReverseOrder(t *testing.T) {
+	lc := lifecycle.New(discard())
+
+	var stopped []string
+	for _, name := range []string{"cache", "repository", "server"} {
+		lc.Append(lifecycle.Hook{
+			Name: name,
+			Stop: func(context.Context) error {
+				stopped = append(stopped, name)
+				return nil
+			},
+		})
+	}
+
+	if err := lc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := lc.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"server", "repository", "cache"}; !slices.Equal(stopped, want) {
+		t.Errorf("stopped %q, want %q", stopped, want)
+	}
+}
+
+func TestStopReportsHungComponent(t *testing.T) {
+	lc := lifecycle.New(discard())
+
+	release := make(chan struct{})
+	defer close(release)
+
+	lc.Go("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	if err := lc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := lc.Stop(ctx)
+
+	var stopErr *lifecycle.StopError
+	if !errors.As(err, &stopErr) || stopErr.Name != "stuck" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop err = %v, want a StopError for stuck with DeadlineExceeded", err)
+	}
+}
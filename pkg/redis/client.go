@@ -0,0 +1,238 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A minimal client for Redis and compatible servers (Valkey, KeyDB, Dragonfly...).
+// It speaks RESP2 over plain TCP and keeps a small pool of idle connections.
+
+// ErrNil is returned by Get when the key doesn't exist.
+var ErrNil = errors.New("redis: nil")
+
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *conn
+
+	mu     sync.Mutex // guards closed against put
+	closed bool
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// New returns a client for the server at addr. Connections are opened lazily.
+// password and db are optional: AUTH is sent when the password isn't empty, SELECT when db isn't 0.
+// timeout bounds dialing and every command, unless the context has an earlier deadline.
+func New(addr, password string, db int, timeout time.Duration) *Client {
+	return &Client{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		idle:     make(chan *conn, 8),
+	}
+}
+
+// Do sends one command and returns its reply, see resp.go for the reply types.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, c.timeout, args)
+
+	var serverErr Error
+	if err != nil && !errors.As(err, &serverErr) {
+		// The connection may be half way through a reply, don't reuse it
+		cn.Close()
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args []string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Cancelling ctx interrupts blocked reads and writes
+	stop := context.AfterFunc(ctx, func() {
+		cn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := writeCommand(cn.w, args); err != nil {
+		return nil, err
+	}
+
+	reply, err := readReply(cn.r)
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		return nil, ctxErr
+	}
+	return reply, err
+}
+
+// conn takes an idle connection or dials a new one.
+func (c *Client) conn(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis dial: %w", err)
+	}
+
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if c.password != "" {
+		if _, err := cn.do(ctx, c.timeout, []string{"AUTH", c.password}); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis AUTH: %w", err)
+		}
+	}
+
+	if c.db != 0 {
+		if _, err := cn.do(ctx, c.timeout, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis SELECT: %w", err)
+		}
+	}
+
+	return cn, nil
+}
+
+// put returns a healthy connection to the pool, or closes it when the pool is full or the client is closed.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		cn.Close()
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+// Close closes the idle connections. Connections in use are closed when they are returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Get returns the value of key, or ErrNil if it doesn't exist.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNil
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: GET replied with %T", errProtocol, reply)
+	}
+	return b, nil
+}
+
+// Set sets key to value. A ttl > 0 is sent as EX in whole seconds (at least one).
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "EX", seconds(ttl))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Del deletes keys and returns how many existed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	reply, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := reply.(int64)
+	return n, nil
+}
+
+// SAdd adds members to the set at key.
+func (c *Client) SAdd(ctx context.Context, key string, members ...string) error {
+	_, err := c.Do(ctx, append([]string{"SADD", key}, members...)...)
+	return err
+}
+
+// SMembers returns all members of the set at key.
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	reply, err := c.Do(ctx, "SMEMBERS", key)
+	if err != nil {
+		return nil, err
+	}
+	arr, _ := reply.([]any)
+	members := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if b, ok := v.([]byte); ok {
+			members = append(members, string(b))
+		}
+	}
+	return members, nil
+}
+
+// Expire sets a ttl on key.
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := c.Do(ctx, "EXPIRE", key, seconds(ttl))
+	return err
+}
+
+// Ping checks the connection to the server.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// seconds formats a ttl for EX / EXPIRE, which take whole seconds
+func seconds(ttl time.Duration) string {
+	return strconv.FormatInt(int64(max(ttl.Round(time.Second), time.Second)/time.Second), 10)
+}
@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testServer is an in-process RESP server, handle returns the raw reply to every command
+type testServer struct {
+	addr     string
+	accepted atomic.Int32
+}
+
+func newTestServer(t *testing.T, handle func(args []string) string) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{addr: ln.Addr().String()}
+
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.accepted.Add(1)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer nc.Close()
+				s.serve(nc, handle)
+			}()
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) serve(nc net.Conn, handle func(args []string) string) {
+	r := bufio.NewReader(nc)
+	for {
+		// A command is an array of bulk strings, readReply reads it as well as a reply
+		cmd, err := readReply(r)
+		if err != nil {
+			return
+		}
+
+		var args []string
+		for _, a := range cmd.([]any) {
+			args = append(args, string(a.([]byte)))
+		}
+
+		if _, err := nc.Write([]byte(handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func TestReplies(t *testing.T) {
+	srv := newTestServer(t, func(args []string) string {
+		switch args[0] {
+		case "GET":
+			if args[1] == "car:1" {
+				return "$3\r\nbmw\r\n"
+			}
+			return "$-1\r\n"
+		case "SMEMBERS":
+			if args[1] == "empty" {
+				return "*-1\r\n"
+			}
+			return "*2\r\n$5\r\ncar:1\r\n$5\r\ncar:2\r\n"
+		case "DEL":
+			return ":2\r\n"
+		case "PING":
+			return "+PONG\r\n"
+		}
+		return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	})
+
+	c := New(srv.addr, "", 0, time.Second)
+	defer c.Close()
+	ctx := context.Background()
+
+	t.Run("bulk", func(t *testing.T) {
+		v, err := c.Get(ctx, "car:1")
+		if err != nil || string(v) != "bmw" {
+			t.Errorf("Get = %q, %v, want \"bmw\"", v, err)
+		}
+	})
+
+	t.Run("nil bulk", func(t *testing.T) {
+		if _, err := c.Get(ctx, "car:2"); !errors.Is(err, ErrNil) {
+			t.Errorf("Get of a missing key err = %v, want ErrNil", err)
+		}
+	})
+
+	t.Run("array", func(t *testing.T) {
+		members, err := c.SMembers(ctx, "tag")
+		if err != nil || !slices.Equal(members, []string{"car:1", "car:2"}) {
+			t.Errorf("SMembers = %q, %v", members, err)
+		}
+	})
+
+	t.Run("nil array", func(t *testing.T) {
+		members, err := c.SMembers(ctx, "empty")
+		if err != nil || len(members) != 0 {
+			t.Errorf("SMembers = %q, %v, want none", members, err)
+		}
+	})
+
+	t.Run("integer", func(t *testing.T) {
+		if n, err := c.Del(ctx, "a", "b"); n != 2 || err != nil {
+			t.Errorf("Del = %d, %v, want 2", n, err)
+		}
+	})
+
+	t.Run("simple string", func(t *testing.T) {
+		if err := c.Ping(ctx); err != nil {
+			t.Errorf("Ping = %v", err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := c.Do(ctx, "INCR", "car:1")
+		var serverErr Error
+		if !errors.As(err, &serverErr) || !strings.HasPrefix(string(serverErr), "WRONGTYPE") {
+			t.Errorf("Do err = %v, want a WRONGTYPE server error", err)
+		}
+	})
+
+	// An error reply leaves the connection usable, every command above went over one
+	if n := srv.accepted.Load(); n != 1 {
+		t.Errorf("server accepted %d connections, want 1", n)
+	}
+}
+
+func TestSetSendsTTL(t *testing.T) {
+	var got []string
+	srv := newTestServer(t, func(args []string) string {
+		got = args
+		return "+OK\r\n"
+	})
+
+	c := New(srv.addr, "", 0, time.Second)
+	defer c.Close()
+
+	if err := c.Set(context.Background(), "k", []byte("v"), 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"SET", "k", "v", "EX", "2"}; !slices.Equal(got, want) {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+}
+
+func TestPutAfterCloseClosesConnection(t *testing.T) {
+	srv := newTestServer(t, func([]string) string { return "+PONG\r\n" })
+
+	c := New(srv.addr, "", 0, time.Second)
+
+	cn, err := c.conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	c.put(cn)
+
+	if len(c.idle) != 0 {
+		t.Error("connection returned after Close went back to the pool")
+	}
+	if _, err := cn.Write([]byte("PING\r\n")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write on a connection returned after Close err = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestReadReplyRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{"bulk without crlf", "$3\r\nbmwXY"},
+		{"bulk longer than its length", "$3\r\nbmw!\r\n"},
+		{"bulk too large", "$999999999999\r\n"},
+		{"array too long", "*2000000\r\n"},
+		{"bad integer", ":12a\r\n"},
+		{"unknown type", "?\r\n"},
+		{"line without cr", "+OK\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readReply(bufio.NewReader(strings.NewReader(tt.reply))); !errors.Is(err, errProtocol) {
+				t.Errorf("readReply(%q) err = %v, want a protocol error", tt.reply, err)
+			}
+		})
+	}
+}
+
+func TestErrorInsideArray(t *testing.T) {
+	reply, err := readReply(bufio.NewReader(strings.NewReader("*2\r\n$1\r\na\r\n-ERR nope\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arr := reply.([]any)
+	if len(arr) != 2 || string(arr[0].([]byte)) != "a" || arr[1] != Error("ERR nope") {
+		t.Errorf("readReply = %#v", reply)
+	}
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RESP2, see https://redis.io/docs/latest/develop/reference/protocol-spec/
+//
+// Replies are decoded as:
+//
+//	simple string -> string
+//	error         -> Error (returned as the error of Do)
+//	integer       -> int64
+//	bulk string   -> []byte, nil for a null bulk string
+//	array         -> []any, nil for a null array
+
+// Error is an error reply of the server, e.g. "WRONGTYPE Operation against a key ...".
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Limits for a corrupt or hostile length: maxBulkSize is redis' own proto-max-bulk-len,
+// maxArrayLen is far above any reply the viewer reads (SMEMBERS of a tag) and keeps
+// the []any allocation of a bad length small
+const (
+	maxBulkSize = 512 << 20
+	maxArrayLen = 1 << 20
+)
+
+var errProtocol = errors.New("redis: protocol error")
+
+// writeCommand writes a command as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args []string) error {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+
+	for _, arg := range args {
+		w.WriteByte('$')
+		w.WriteString(strconv.Itoa(len(arg)))
+		w.WriteString("\r\n")
+		w.WriteString(arg)
+		w.WriteString("\r\n")
+	}
+
+	return w.Flush()
+}
+
+// readReply reads one reply. A server error reply is returned as Error,
+// any other error means the connection can't be used anymore.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad integer %q", errProtocol, line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxBulkSize {
+			return nil, fmt.Errorf("%w: bad bulk length %q", errProtocol, line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2) // with the trailing \r\n
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string longer than %d bytes", errProtocol, n)
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxArrayLen {
+			return nil, fmt.Errorf("%w: bad array length %q", errProtocol, line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			v, err := readReply(r)
+			var serverErr Error
+			if errors.As(err, &serverErr) {
+				arr[i] = serverErr // an error inside an array doesn't fail the whole reply
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	}
+
+	return nil, fmt.Errorf("%w: unexpected reply type %q", errProtocol, line[0])
+}
+
+// readLine reads a line terminated by \r\n, without the terminator.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: line without \\r\\n", errProtocol)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,102 @@
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Context aware and generic version of golang.org/x/sync/singleflight.
+// Concurrent callers with the same key share one execution of fn and its result,
+// but every caller still waits only as long as its own context allows.
+
+type call[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Group deduplicates in-flight calls by key. The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// Do executes fn once for all concurrent callers with the same key.
+// fn gets a context that keeps the values of the first caller's ctx, but is cancelled
+// only when every waiting caller has gone, so one impatient visitor doesn't fail the others.
+// shared reports whether the caller joined a call started by somebody else.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+
+	c, shared := g.calls[key]
+	if shared {
+		c.waiters++
+	} else {
+		fnCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call[V]{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+		}
+		g.calls[key] = c
+
+		go g.run(fnCtx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody is interested anymore, stop the work and let the next caller start a fresh one
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+
+		var zero V
+		return zero, ctx.Err(), shared
+	}
+}
+
+// Waiters reports how many callers wait for the call in flight for key, 0 if there is none.
+func (g *Group[K, V]) Waiters(key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, c *call[V], fn func(ctx context.Context) (V, error)) {
+	defer c.cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: panic in call: %v", r)
+		}
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	c.val, c.err = fn(ctx)
+}
@@ -0,0 +1,116 @@
+package singleflight_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitea.kood.tech/ivanandreev/viewer/pkg/singleflight"
+)
+
+// waitWaiters waits until n callers have joined the call for key
+func waitWaiters[V any](t *testing.T, g *singleflight.Group[string, V], key string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Waiters(key) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers joined, want %d", g.Waiters(key), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentCallersShareOneCall(t *testing.T) {
+	const callers = 50
+
+	var (
+		g       singleflight.Group[string, int]
+		calls   atomic.Int32
+		shared  atomic.Int32
+		wrong   atomic.Int32
+		wg      sync.WaitGroup
+		release = make(chan struct{})
+	)
+
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, s := g.Do(context.Background(), "key", func(context.Context) (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			if v != 42 || err != nil {
+				wrong.Add(1)
+			}
+			if s {
+				shared.Add(1)
+			}
+		}()
+	}
+
+	waitWaiters(t, &g, "key", callers)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	if n := wrong.Load(); n > 0 {
+		t.Errorf("%d callers didn't get the result", n)
+	}
+	if n := shared.Load(); n != callers-1 {
+		t.Errorf("%d callers report a shared call, want %d", n, callers-1)
+	}
+	if n := g.Waiters("key"); n != 0 {
+		t.Errorf("%d waiters left after the call, want 0", n)
+	}
+}
+
+func TestCallCancelledWhenEveryoneLeaves(t *testing.T) {
+	var g singleflight.Group[string, int]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(ctx, "key", func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+			return 0, ctx.Err()
+		})
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("call wasn't cancelled after the only caller left")
+	}
+
+	// The key is free again, the next caller starts a fresh call
+	v, err, shared := g.Do(context.Background(), "key", func(context.Context) (int, error) {
+		return 42, nil
+	})
+	if v != 42 || err != nil || shared {
+		t.Errorf("Do = %d, %v, shared %v, want 42, nil, false", v, err, shared)
+	}
+}
+
+func TestPanicBecomesError(t *testing.T) {
+	var g singleflight.Group[string, int]
+
+	_, err, _ := g.Do(context.Background(), "key", func(context.Context) (int, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("want an error from a panicking call")
+	}
+}
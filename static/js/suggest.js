@@ -0,0 +1,144 @@
+// Autocomplete for the header search box.
+// Progressive enhancement: without this script (or when /api/suggest fails)
+// the form still submits to /catalog as usual.
+(function () {
+    "use strict";
+
+    var form = document.querySelector(".nav-search-form");
+    var input = form && form.querySelector("input[name=q]");
+    if (!input || !window.fetch) {
+        return;
+    }
+
+    var list = document.createElement("ul");
+    list.className = "suggest-list";
+    list.id = "search-suggestions";
+    list.setAttribute("role", "listbox");
+    list.hidden = true;
+    form.appendChild(list);
+
+    input.setAttribute("autocomplete", "off");
+    input.setAttribute("role", "combobox");
+    input.setAttribute("aria-autocomplete", "list");
+    input.setAttribute("aria-controls", list.id);
+    input.setAttribute("aria-expanded", "false");
+
+    var timer = null;
+    var pending = null;
+    var active = -1;
+
+    function close() {
+        list.hidden = true;
+        list.innerHTML = "";
+        active = -1;
+        input.setAttribute("aria-expanded", "false");
+        input.removeAttribute("aria-activedescendant");
+    }
+
+    function render(items) {
+        list.innerHTML = "";
+        active = -1;
+
+        items.forEach(function (item, i) {
+            var li = document.createElement("li");
+            li.id = "suggestion-" + i;
+            li.setAttribute("role", "option");
+
+            var link = document.createElement("a");
+            link.href = item.url;
+            link.textContent = item.text;
+
+            var type = document.createElement("span");
+            type.className = "suggest-type";
+            type.textContent = item.type;
+
+            link.appendChild(type);
+            li.appendChild(link);
+            list.appendChild(li);
+        });
+
+        list.hidden = items.length === 0;
+        input.setAttribute("aria-expanded", String(items.length > 0));
+    }
+
+    function highlight(i) {
+        var items = list.children;
+        if (items.length === 0) {
+            return;
+        }
+        if (active >= 0) {
+            items[active].classList.remove("active");
+        }
+        active = (i + items.length) % items.length;
+        items[active].classList.add("active");
+        input.setAttribute("aria-activedescendant", items[active].id);
+    }
+
+    function load(query) {
+        if (pending) {
+            pending.abort();
+        }
+        pending = new AbortController();
+
+        fetch("/api/suggest?q=" + encodeURIComponent(query), { signal: pending.signal })
+            .then(function (res) {
+                if (!res.ok) {
+                    throw new Error(res.status);
+                }
+                return res.json();
+            })
+            .then(function (data) {
+                // Answers may come out of order, keep only the one for the current text.
+                // The server cuts the query at 50 code points, slice would count UTF-16 units.
+                if (data.query === Array.from(input.value.trim()).slice(0, 50).join("")) {
+                    render(data.suggestions);
+                }
+            })
+            .catch(function (err) {
+                if (err.name !== "AbortError") {
+                    close();
+                }
+            });
+    }
+
+    input.addEventListener("input", function () {
+        clearTimeout(timer);
+        var query = input.value.trim();
+        if (query === "") {
+            close();
+            return;
+        }
+        timer = setTimeout(function () { load(query); }, 150);
+    });
+
+    input.addEventListener("keydown", function (e) {
+        if (list.hidden) {
+            return;
+        }
+        switch (e.key) {
+            case "ArrowDown":
+                e.preventDefault();
+                highlight(active + 1);
+                break;
+            case "ArrowUp":
+                e.preventDefault();
+                highlight(active - 1);
+                break;
+            case "Enter":
+                // Without a highlighted suggestion Enter searches as usual
+                if (active >= 0) {
+                    e.preventDefault();
+                    window.location.href = list.children[active].querySelector("a").href;
+                }
+                break;
+            case "Escape":
+                close();
+                break;
+        }
+    });
+
+    // Delayed, so a click on a suggestion lands before the list is gone
+    input.addEventListener("blur", function () {
+        setTimeout(close, 150);
+    });
+})();